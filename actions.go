@@ -0,0 +1,429 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// seriesResult describes the outcome of generating or uploading one series.
+type seriesResult struct {
+	GUID     string
+	Title    string
+	Author   string
+	Episodes int
+	// Filtered counts episodes dropped by the include/exclude tag filters
+	Filtered int
+	Action   string
+	Location string
+	// Unchanged lists destinations skipped because they were up to date
+	Unchanged []string
+	// BytesUploaded is the uncompressed size of everything uploaded
+	BytesUploaded int64
+	Warnings      []string
+	Err           error
+}
+
+// log records the outcome so it shows up in the log file and debug view.
+func (r seriesResult) log() {
+	if r.Err != nil {
+		slog.Error("series operation failed", "guid", r.GUID, "err", r.Err)
+		return
+	}
+	slog.Info("series feed "+r.Action, "guid", r.GUID, "location", r.Location, "episodes", r.Episodes)
+}
+
+// skipped reports whether the series was skipped because it no longer
+// exists upstream, as opposed to failing.
+func (r seriesResult) skipped() bool {
+	var notFound *SeriesNotFoundError
+	return errors.As(r.Err, &notFound)
+}
+
+func (r seriesResult) String() string {
+	if r.Err != nil {
+		if r.Location != "" {
+			return fmt.Sprintf("Error: %v (succeeded for %s)", r.Err, r.Location)
+		}
+		return fmt.Sprintf("Error: %v", r.Err)
+	}
+	if r.Location == "" && len(r.Unchanged) > 0 {
+		return fmt.Sprintf("RSS feed unchanged, skipped upload to %s (%s by %s, %s)", strings.Join(r.Unchanged, ", "), r.Title, r.Author, r.episodeCount()) + formatWarnings(r.Warnings)
+	}
+	msg := fmt.Sprintf("RSS feed %s to %s", r.Action, r.Location)
+	if r.Title != "" {
+		msg += fmt.Sprintf(" (%s by %s, %s)", r.Title, r.Author, r.episodeCount())
+	}
+	if len(r.Unchanged) > 0 {
+		msg += fmt.Sprintf("; unchanged, skipped upload to %s", strings.Join(r.Unchanged, ", "))
+	}
+	return msg + formatWarnings(r.Warnings)
+}
+
+func (r seriesResult) episodeCount() string {
+	if r.Filtered > 0 {
+		return fmt.Sprintf("%d of %d episodes passed tag filters", r.Episodes-r.Filtered, r.Episodes)
+	}
+	return fmt.Sprintf("%d episodes", r.Episodes)
+}
+
+func formatWarnings(warnings []string) string {
+	if len(warnings) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\nWarning: %s", strings.Join(warnings, "; "))
+}
+
+// buildSeriesFeed fetches the series and renders its RSS feed, filling in
+// the descriptive fields of result along the way.
+func buildSeriesFeed(ctx context.Context, series Series, settings Settings, result *seriesResult) (*SeriesData, string, error) {
+	seriesData, err := fetchSeries(ctx, series, settings)
+	if err != nil {
+		return nil, "", err
+	}
+
+	rssXML, _, err := renderSeriesFeed(seriesData, series, settings, result)
+	if err != nil {
+		return nil, "", err
+	}
+	return seriesData, rssXML, nil
+}
+
+// fetchSeries fetches the series data, passing SeriesNotFoundError through
+// unwrapped so the series can be reported as skipped.
+func fetchSeries(ctx context.Context, series Series, settings Settings) (*SeriesData, error) {
+	seriesData, err := fetchSeriesData(ctx, series.GUID, settings)
+	var notFound *SeriesNotFoundError
+	if errors.As(err, &notFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch series data: %w", err)
+	}
+	return seriesData, nil
+}
+
+// renderSeriesFeed renders already fetched series data as an RSS feed and
+// returns it along with the episodes that made it into the feed.
+func renderSeriesFeed(seriesData *SeriesData, series Series, settings Settings, result *seriesResult) (string, []Episode, error) {
+	feed, items := assembleSeriesFeed(seriesData, series, settings, result)
+	rssXML, err := marshalSeriesFeed(feed, settings)
+	if err != nil {
+		return "", nil, err
+	}
+	return rssXML, items, nil
+}
+
+// assembleSeriesFeed applies the series config and tag filters to fetched
+// data and builds the feed structure, filling in the descriptive fields of
+// result. It also returns the episodes that made it into the feed.
+func assembleSeriesFeed(seriesData *SeriesData, series Series, settings Settings, result *seriesResult) (*RSSFeed, []Episode) {
+	applySeriesConfig(seriesData, series)
+	result.Title = seriesData.Title
+	result.Author = seriesData.Author
+	result.Episodes = len(seriesData.Episodes)
+
+	include, exclude := series.tagFilters(settings)
+	seriesData.Episodes = filterEpisodesByTags(seriesData.Episodes, include, exclude)
+	result.Filtered = result.Episodes - len(seriesData.Episodes)
+
+	var items []Episode
+	opts := feedOptions(series, settings, false, time.Now())
+	opts.OnWarning = func(msg string) { result.Warnings = append(result.Warnings, msg) }
+	opts.OnItem = func(episode Episode) { items = append(items, episode) }
+
+	return buildRSSFeed(seriesData, opts), items
+}
+
+// marshalSeriesFeed serializes the feed in the configured format and checks
+// it against max_feed_bytes.
+func marshalSeriesFeed(feed *RSSFeed, settings Settings) (string, error) {
+	marshal := marshalRSSFeed
+	if settings.FeedFormat == feedFormatJSON {
+		marshal = marshalJSONFeed
+	}
+	document, err := marshal(feed)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate feed: %w", err)
+	}
+	if limit := settings.maxFeedBytes(); int64(len(document)) > limit {
+		return "", fmt.Errorf("generated feed is %d bytes, over the max_feed_bytes limit of %d", len(document), limit)
+	}
+	return document, nil
+}
+
+// applySeriesConfig applies per-series config overrides to the fetched data.
+func applySeriesConfig(seriesData *SeriesData, series Series) {
+	if series.TitleOverride != "" {
+		seriesData.Title = series.TitleOverride
+	}
+	if series.DescriptionOverride != "" {
+		seriesData.Description = series.DescriptionOverride
+	}
+
+	blocked := make(map[string]bool, len(series.BlockedEpisodes))
+	for _, guid := range series.BlockedEpisodes {
+		blocked[guid] = true
+	}
+	for i := range seriesData.Episodes {
+		if blocked[seriesData.Episodes[i].GUID] {
+			seriesData.Episodes[i].Blocked = true
+		}
+	}
+}
+
+// filterEpisodesByTags keeps episodes that carry one of the include tags
+// (or all episodes if include is empty), then drops any carrying an exclude
+// tag; exclude wins when an episode matches both. Both the episode's own
+// tags and its series tags count, compared case-insensitively.
+func filterEpisodesByTags(episodes []Episode, include, exclude []string) []Episode {
+	if len(include) == 0 && len(exclude) == 0 {
+		return episodes
+	}
+
+	hasAny := func(episode Episode, tags []string) bool {
+		for _, want := range tags {
+			for _, tag := range slices.Concat(episode.Tags, episode.SeriesTags) {
+				if strings.EqualFold(tag, want) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	var kept []Episode
+	for _, episode := range episodes {
+		if len(include) > 0 && !hasAny(episode, include) {
+			continue
+		}
+		if hasAny(episode, exclude) {
+			continue
+		}
+		kept = append(kept, episode)
+	}
+	return kept
+}
+
+func generateSeries(ctx context.Context, series Series, settings Settings) (result seriesResult) {
+	result = seriesResult{GUID: series.GUID, Action: "written"}
+	defer func() { result.log() }()
+
+	_, rssXML, err := buildSeriesFeed(ctx, series, settings, &result)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	return writeSeriesFeed(ctx, series, settings, rssXML, result)
+}
+
+// generateEpisodes writes a feed for series that contains only episodes.
+func generateEpisodes(ctx context.Context, series Series, settings Settings, seriesData *SeriesData, episodes []Episode) (result seriesResult) {
+	result = seriesResult{GUID: series.GUID, Action: "written"}
+	defer func() { result.log() }()
+
+	filtered := *seriesData
+	filtered.Episodes = episodes
+	rssXML, _, err := renderSeriesFeed(&filtered, series, settings, &result)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	return writeSeriesFeed(ctx, series, settings, rssXML, result)
+}
+
+// writeSeriesFeed saves the rendered feed to the output directory, unless
+// ctx was canceled while the feed was being built.
+func writeSeriesFeed(ctx context.Context, series Series, settings Settings, rssXML string, result seriesResult) seriesResult {
+	if err := ctx.Err(); err != nil {
+		result.Err = fmt.Errorf("not writing RSS file: %w", err)
+		return result
+	}
+	outputPath, err := writeFeedFile(settings.OutputDir, localFilename(series, settings.feedExtension()), rssXML, settings.fileMode())
+	if err != nil {
+		result.Err = fmt.Errorf("failed to write RSS file: %w", err)
+		return result
+	}
+	result.Location = outputPath
+
+	return result
+}
+
+func uploadSeries(ctx context.Context, s3Client *S3Client, series Series, settings Settings) (result seriesResult) {
+	result = seriesResult{GUID: series.GUID, Action: "uploaded"}
+	defer func() { result.log() }()
+
+	seriesData, err := fetchSeries(ctx, series, settings)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if settings.MirrorCover {
+		n, err := mirrorCover(ctx, s3Client, seriesData, series.S3Path, settings)
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("using original cover URL: %v", err))
+		}
+		result.BytesUploaded += n
+	}
+	rssXML, items, err := renderSeriesFeed(seriesData, series, settings, &result)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	// Every destination's feed links the chapters next to the primary path,
+	// so they are uploaded there once, before any feed refers to them
+	if settings.Chapters {
+		if err := uploadChapters(ctx, s3Client, items, series.S3Path); err != nil {
+			result.Err = err
+			return result
+		}
+	}
+
+	// Upload to every destination; a failing one does not stop the rest
+	var uploaded []string
+	var errs []error
+	for _, s3Path := range series.destinations() {
+		changed := true
+		if settings.ForceUpload {
+			err = s3Client.UploadFeed(ctx, rssXML, s3Path, settings.feedContentType())
+		} else {
+			changed, err = s3Client.UploadFeedIfChanged(ctx, rssXML, s3Path, settings.feedContentType())
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to upload to %s: %w", s3Path, err))
+			continue
+		}
+		if !changed {
+			result.Unchanged = append(result.Unchanged, s3Path)
+			continue
+		}
+		uploaded = append(uploaded, s3Path)
+		result.BytesUploaded += int64(len(rssXML))
+	}
+	result.Location = strings.Join(uploaded, ", ")
+	result.Err = errors.Join(errs...)
+
+	return result
+}
+
+// undoUpload restores the previous version of every destination of series.
+func undoUpload(ctx context.Context, s3Client *S3Client, series Series) (result seriesResult) {
+	result = seriesResult{GUID: series.GUID, Action: "restored"}
+	defer func() { result.log() }()
+
+	var restored []string
+	var errs []error
+	for _, s3Path := range series.destinations() {
+		versionID, err := s3Client.RestorePreviousVersion(ctx, s3Path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		restored = append(restored, fmt.Sprintf("%s (version %s)", s3Path, versionID))
+	}
+	result.Location = strings.Join(restored, ", ")
+	result.Err = errors.Join(errs...)
+
+	return result
+}
+
+// writeFeedFile writes the feed into outputDir, creating the directory if
+// needed. An empty outputDir means the current working directory. The file
+// is written under a temporary name and renamed into place, so readers
+// never see a partial feed.
+func writeFeedFile(outputDir, filename, content string, mode os.FileMode) (string, error) {
+	outputPath := filename
+	if outputDir != "" {
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create output directory: %w", err)
+		}
+		outputPath = filepath.Join(outputDir, filename)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(outputPath), "."+filepath.Base(outputPath)+".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	// Removing fails harmlessly once the rename has succeeded
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), outputPath); err != nil {
+		return "", err
+	}
+
+	return outputPath, nil
+}
+
+// displayPath shows the bucket and full key so that series whose keys share
+// a basename remain distinguishable in the list.
+func displayPath(s3Path string) string {
+	return strings.TrimPrefix(s3Path, "s3://")
+}
+
+// localFilename derives a unique local filename from the bucket and full
+// S3 key, e.g. s3://bucket/a/feed.rss becomes bucket__a__feed.rss. Falls
+// back to the series GUID when the key cannot be used or lacks the feed
+// format's extension.
+func localFilename(series Series, ext string) string {
+	bucket, key, err := parseS3Path(series.S3Path)
+	if err != nil || !strings.HasSuffix(key, ext) {
+		return series.GUID + ext
+	}
+
+	return escapeFilename(bucket + "/" + key)
+}
+
+// escapeFilename maps an S3 path to a filename without collisions: "/"
+// becomes "__" and every other byte outside [A-Za-z0-9.-], including "_"
+// itself, becomes "_" and two hex digits. An "_" is always followed by
+// another "_" or a hex digit, so the name decodes back to a single path.
+func escapeFilename(name string) string {
+	var sb strings.Builder
+	for i := 0; i < len(name); i++ {
+		switch c := name[i]; {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '.', c == '-':
+			sb.WriteByte(c)
+		case c == '/':
+			sb.WriteString("__")
+		default:
+			fmt.Fprintf(&sb, "_%02x", c)
+		}
+	}
+	return sb.String()
+}
+
+// runAction calls action for series, turning a panic into a failed result so
+// one bad series cannot take down a batch.
+func runAction(action func(Series) seriesResult, series Series) (result seriesResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("action panicked", "guid", series.GUID, "panic", r)
+			result = seriesResult{GUID: series.GUID, Err: fmt.Errorf("internal error: %v", r)}
+		}
+	}()
+	return action(series)
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalFilenameNoCollision(t *testing.T) {
+	paths := []string{
+		"s3://b/a/feed.rss",
+		"s3://b/b/feed.rss",
+		"s3://b/a_feed.rss",
+		"s3://b/a__feed.rss",
+		"s3://other/a/feed.rss",
+		"s3://b/a/_5ffeed.rss",
+		"s3://b/a/ feed.rss",
+	}
+
+	seen := make(map[string]string)
+	for _, p := range paths {
+		name := localFilename(Series{GUID: "guid", S3Path: p}, ".rss")
+		if other, ok := seen[name]; ok {
+			t.Errorf("localFilename(%q) = %q, same as for %q", p, name, other)
+		}
+		seen[name] = p
+	}
+}
+
+func TestLocalFilename(t *testing.T) {
+	tests := []struct {
+		s3Path string
+		want   string
+	}{
+		{"s3://bucket/podcasts/show/feed.rss", "bucket__podcasts__show__feed.rss"},
+		{"s3://bucket/my_feed.rss", "bucket__my_5ffeed.rss"},
+		{"s3://bucket/feed.json", "guid.rss"},
+		{"not-an-s3-path", "guid.rss"},
+	}
+	for _, tt := range tests {
+		if got := localFilename(Series{GUID: "guid", S3Path: tt.s3Path}, ".rss"); got != tt.want {
+			t.Errorf("localFilename(%q) = %q, want %q", tt.s3Path, got, tt.want)
+		}
+	}
+}
+
+func TestRenderSeriesFeedOverrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		series    Series
+		wantTitle string
+		wantDesc  string
+	}{
+		{"api values", Series{}, "API title", "API description"},
+		{"overrides", Series{TitleOverride: "My title", DescriptionOverride: "My description"}, "My title", "My description"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seriesData := &SeriesData{Title: "API title", Description: "API description"}
+			var result seriesResult
+			rssXML, _, err := renderSeriesFeed(seriesData, tt.series, Settings{}, &result)
+			if err != nil {
+				t.Fatalf("renderSeriesFeed() error = %v", err)
+			}
+			if result.Title != tt.wantTitle {
+				t.Errorf("result.Title = %q, want %q", result.Title, tt.wantTitle)
+			}
+			if !strings.Contains(rssXML, "<title>"+tt.wantTitle+"</title>") {
+				t.Errorf("feed does not have title %q", tt.wantTitle)
+			}
+			if !strings.Contains(rssXML, "<description>"+tt.wantDesc+"</description>") {
+				t.Errorf("feed does not have description %q", tt.wantDesc)
+			}
+		})
+	}
+}
+
+func TestWriteFeedFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "feeds", "nested")
+
+	got, err := writeFeedFile(dir, "show.rss", "<rss/>", 0o644)
+	if err != nil {
+		t.Fatalf("writeFeedFile() error = %v", err)
+	}
+	want := filepath.Join(dir, "show.rss")
+	if got != want {
+		t.Errorf("writeFeedFile() path = %q, want %q", got, want)
+	}
+	content, err := os.ReadFile(want)
+	if err != nil {
+		t.Fatalf("feed was not written to the output directory: %v", err)
+	}
+	if string(content) != "<rss/>" {
+		t.Errorf("feed content = %q, want %q", content, "<rss/>")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("output directory has %d entries, want only the feed", len(entries))
+	}
+}
+
+func TestWriteFeedFileMode(t *testing.T) {
+	dir := t.TempDir()
+	settings := Settings{FileMode: "0600"}
+	path, err := writeFeedFile(dir, "show.rss", "<rss/>", settings.fileMode())
+	if err != nil {
+		t.Fatalf("writeFeedFile() error = %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := info.Mode().Perm(); got != 0o600 {
+		t.Errorf("file mode = %o, want 600", got)
+	}
+	if got := (Settings{}).fileMode(); got != defaultFileMode {
+		t.Errorf("default fileMode() = %o, want %o", got, defaultFileMode)
+	}
+}
+
+func TestWriteFeedFileRenameFails(t *testing.T) {
+	dir := t.TempDir()
+	// Renaming a file over a non-empty directory fails
+	target := filepath.Join(dir, "show.rss")
+	if err := os.MkdirAll(filepath.Join(target, "keep"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := writeFeedFile(dir, "show.rss", "<rss/>", 0o644); err == nil {
+		t.Fatal("writeFeedFile() succeeded, want the rename error")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "show.rss" || !entries[0].IsDir() {
+		t.Errorf("output directory = %v, want only the untouched target and no temporary file", entries)
+	}
+}
+
+func TestFilterEpisodesByTags(t *testing.T) {
+	episodes := []Episode{
+		{GUID: "news", Tags: []string{"News"}},
+		{GUID: "sports", Tags: []string{"sports"}},
+		{GUID: "news-sports", Tags: []string{"news", "sports"}},
+		{GUID: "series-tagged", SeriesTags: []string{"news"}},
+		{GUID: "untagged"},
+	}
+	tests := []struct {
+		name             string
+		include, exclude []string
+		want             string
+	}{
+		{"no filters", nil, nil, "news,sports,news-sports,series-tagged,untagged"},
+		{"include", []string{"news"}, nil, "news,news-sports,series-tagged"},
+		{"include any of several", []string{"NEWS", "sports"}, nil, "news,sports,news-sports,series-tagged"},
+		{"exclude", nil, []string{"sports"}, "news,series-tagged,untagged"},
+		{"exclude wins", []string{"news"}, []string{"sports"}, "news,series-tagged"},
+		{"nothing matches", []string{"weather"}, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, episode := range filterEpisodesByTags(episodes, tt.include, tt.exclude) {
+				got = append(got, episode.GUID)
+			}
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("filterEpisodesByTags() = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaxFeedBytes(t *testing.T) {
+	huge := strings.Repeat("word ", 1000)
+	settings := apiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {"guid": "show", "title": "Show", "description": "` + huge + `"}}`))
+	})
+	settings.MaxFeedBytes = 2000
+	settings.OutputDir = t.TempDir()
+	series := Series{GUID: "show", S3Path: "s3://bucket/show.rss"}
+
+	result := generateSeries(context.Background(), series, settings)
+	if result.Err == nil || !strings.Contains(result.Err.Error(), "max_feed_bytes limit of 2000") {
+		t.Errorf("generateSeries() error = %v, want the size limit error", result.Err)
+	}
+	if entries, _ := os.ReadDir(settings.OutputDir); len(entries) != 0 {
+		t.Errorf("an oversized feed was written: %v", entries)
+	}
+
+	fake := newFakeS3()
+	result = uploadSeries(context.Background(), fake.client(), series, settings)
+	if result.Err == nil || len(fake.puts) != 0 {
+		t.Errorf("uploadSeries() error = %v with %d uploads, want the size limit error and none", result.Err, len(fake.puts))
+	}
+
+	settings.MaxFeedBytes = 0
+	if result := generateSeries(context.Background(), series, settings); result.Err != nil {
+		t.Errorf("generateSeries() under the default limit error = %v", result.Err)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"time"
+)
+
+// feedState is what was last done to a series' feed in this session.
+type feedState int
+
+const (
+	feedUntouched feedState = iota
+	feedGenerated
+	feedUploaded
+)
+
+// glyph marks the state in the series list.
+func (s feedState) glyph() string {
+	switch s {
+	case feedGenerated:
+		return "↓"
+	case feedUploaded:
+		return "↑"
+	}
+	return " "
+}
+
+func (s feedState) String() string {
+	switch s {
+	case feedGenerated:
+		return "Generated locally"
+	case feedUploaded:
+		return "Uploaded"
+	}
+	return "Untouched"
+}
+
+// seriesActivity is the latest state of one series and when it was reached.
+type seriesActivity struct {
+	state feedState
+	at    time.Time
+}
+
+// recordActivity updates activity, keyed by series GUID so it survives
+// sorting, from a finished action. Failures leave the previous state, and
+// undoing an upload clears it since S3 no longer holds what was uploaded.
+func recordActivity(activity map[string]seriesActivity, r seriesResult, at time.Time) {
+	if r.Err != nil {
+		return
+	}
+	switch r.Action {
+	case "written":
+		activity[r.GUID] = seriesActivity{state: feedGenerated, at: at}
+	case "uploaded":
+		activity[r.GUID] = seriesActivity{state: feedUploaded, at: at}
+	case "restored":
+		delete(activity, r.GUID)
+	}
+}
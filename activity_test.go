@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestActivityTransitions(t *testing.T) {
+	m := model{
+		series:   []Series{{GUID: "a", S3Path: "s3://bucket/a.rss"}, {GUID: "b", S3Path: "s3://bucket/b.rss"}},
+		metadata: make([]seriesMetadata, 2),
+		activity: make(map[string]seriesActivity),
+	}
+	state := func(guid string) feedState { return m.activity[guid].state }
+	apply := func(r seriesResult) {
+		next, _ := m.Update(actionResult(r))
+		m = next.(model)
+	}
+
+	steps := []struct {
+		name   string
+		result seriesResult
+		want   feedState
+	}{
+		{"generated", seriesResult{GUID: "a", Action: "written"}, feedGenerated},
+		{"uploaded", seriesResult{GUID: "a", Action: "uploaded"}, feedUploaded},
+		{"failed upload keeps the state", seriesResult{GUID: "a", Action: "uploaded", Err: errors.New("boom")}, feedUploaded},
+		{"generated after upload", seriesResult{GUID: "a", Action: "written"}, feedGenerated},
+		{"undo clears the state", seriesResult{GUID: "a", Action: "restored"}, feedUntouched},
+	}
+	for _, step := range steps {
+		apply(step.result)
+		if got := state("a"); got != step.want {
+			t.Errorf("after %s: state = %s, want %s", step.name, got, step.want)
+		}
+	}
+	if got := state("b"); got != feedUntouched {
+		t.Errorf("untouched series state = %s", got)
+	}
+
+	before := time.Now()
+	apply(seriesResult{GUID: "a", Action: "uploaded"})
+	apply(seriesResult{GUID: "b", Action: "written"})
+	if at := m.activity["a"].at; at.Before(before) {
+		t.Errorf("activity time %v is before the action", at)
+	}
+	if glyph := state("a").glyph() + state("b").glyph(); glyph != "↑↓" {
+		t.Errorf("glyphs = %q, want ↑↓", glyph)
+	}
+
+	m = m.applyConfig(&SeriesConfig{Series: []Series{{GUID: "b", S3Path: "s3://bucket/b.rss"}, {GUID: "c", S3Path: "s3://bucket/c.rss"}}}, "c", &SeriesData{})
+	if _, ok := m.activity["a"]; ok {
+		t.Error("activity of a removed series survived the reload")
+	}
+	if state("b") != feedGenerated || state("c") != feedUntouched {
+		t.Errorf("after reload: b = %s, c = %s, want generated and untouched", state("b"), state("c"))
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+type adderStep int
+
+const (
+	adderGUID adderStep = iota
+	adderS3Path
+	adderChecking
+	adderConfirm
+	adderSaving
+)
+
+// seriesAdder is the form for adding a series to the config: it asks for
+// the GUID and S3 path, fetches the series to preview its title and appends
+// the entry once confirmed.
+type seriesAdder struct {
+	step   adderStep
+	guid   string
+	s3Path string
+	title  string
+	data   *SeriesData
+	err    error
+}
+
+// input returns the field the current step edits, if any.
+func (a *seriesAdder) input() *string {
+	switch a.step {
+	case adderGUID:
+		return &a.guid
+	case adderS3Path:
+		return &a.s3Path
+	}
+	return nil
+}
+
+func (a *seriesAdder) view() string {
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("205"))
+	statusStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+
+	field := func(label, value string, active bool) string {
+		if active {
+			value += "_"
+		}
+		return fmt.Sprintf("%-8s %s\n", label, value)
+	}
+
+	s := headerStyle.Render("Add Series") + "\n\n"
+	s += field("GUID:", a.guid, a.step == adderGUID)
+	s += field("S3 path:", a.s3Path, a.step == adderS3Path)
+	if a.title != "" {
+		s += field("Title:", a.title, false)
+	}
+	if a.err != nil {
+		s += "\n" + errorStyle.Render(a.err.Error()) + "\n"
+	}
+
+	help := "enter: next • esc: cancel"
+	switch a.step {
+	case adderChecking:
+		help = "fetching series..."
+	case adderConfirm:
+		help = "y: add to config • n/esc: cancel"
+	case adderSaving:
+		help = "saving..."
+	}
+	s += "\n" + statusStyle.Render(help)
+	return s
+}
+
+// validateNewSeries checks a series entered in the form before its GUID is
+// looked up upstream.
+func validateNewSeries(guid, s3Path string, existing []Series) error {
+	if guid == "" {
+		return fmt.Errorf("GUID is required")
+	}
+	for _, value := range []string{guid, s3Path} {
+		if strings.ContainsFunc(value, func(r rune) bool { return r == '"' || r == '\\' || unicode.IsControl(r) }) {
+			return fmt.Errorf("%q contains characters that are not allowed", value)
+		}
+	}
+	for _, series := range existing {
+		if series.GUID == guid {
+			return fmt.Errorf("series %s is already configured", guid)
+		}
+	}
+	if _, _, err := parseS3Path(s3Path); err != nil {
+		return err
+	}
+	return nil
+}
+
+// appendSeriesToConfig adds a [[series]] table to the end of the config
+// file, leaving the existing content and comments untouched. The file is
+// created if needed. Values must have passed validateNewSeries, so they
+// can be quoted as TOML basic strings directly.
+func appendSeriesToConfig(path, guid, s3Path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	entry := fmt.Sprintf("\n[[series]]\nguid = \"%s\"\ns3_path = \"%s\"\n", guid, s3Path)
+	if _, err := f.WriteString(entry); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return f.Close()
+}
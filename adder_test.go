@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestValidateNewSeries(t *testing.T) {
+	existing := []Series{{GUID: "taken", S3Path: "s3://bucket/taken.rss"}}
+	if err := validateNewSeries("new", "s3://bucket/new.rss", existing); err != nil {
+		t.Errorf("validateNewSeries() error = %v", err)
+	}
+
+	tests := []struct {
+		name, guid, s3Path string
+	}{
+		{"empty GUID", "", "s3://bucket/new.rss"},
+		{"duplicate GUID", "taken", "s3://bucket/other.rss"},
+		{"quote in GUID", `new"`, "s3://bucket/new.rss"},
+		{"newline in path", "new", "s3://bucket/new.rss\n"},
+		{"not an S3 path", "new", "https://bucket/new.rss"},
+		{"no key", "new", "s3://bucket"},
+	}
+	for _, tt := range tests {
+		if err := validateNewSeries(tt.guid, tt.s3Path, existing); err == nil {
+			t.Errorf("%s: validateNewSeries() succeeded, want an error", tt.name)
+		}
+	}
+}
+
+func TestAppendSeriesToConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "series.toml")
+	original := "# My feeds\n[[series]]\nguid = \"old\" # keep me\ns3_path = \"s3://bucket/old.rss\"\n"
+	if err := os.WriteFile(path, []byte(original), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := appendSeriesToConfig(path, "new", "s3://bucket/new.rss"); err != nil {
+		t.Fatalf("appendSeriesToConfig() error = %v", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(content), original) {
+		t.Errorf("existing content was changed:\n%s", content)
+	}
+	config, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if len(config.Series) != 2 || config.Series[1].GUID != "new" || config.Series[1].S3Path != "s3://bucket/new.rss" {
+		t.Errorf("series = %+v, want the old and the new entry", config.Series)
+	}
+
+	if err := appendSeriesToConfig(filepath.Join(path, "nested.toml"), "x", "s3://bucket/x.rss"); err == nil {
+		t.Error("appending below a file succeeded, want an error")
+	}
+}
+
+func TestAdderChecksGUID(t *testing.T) {
+	settings := apiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/known.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"data": {"guid": "known", "title": "Known Show"}}`))
+	})
+
+	submit := func(guid string) model {
+		m := model{
+			ctx:      context.Background(),
+			settings: settings,
+			adder:    &seriesAdder{step: adderS3Path, guid: guid, s3Path: "s3://bucket/show.rss"},
+		}
+		next, cmd := m.updateAdder(tea.KeyMsg{Type: tea.KeyEnter})
+		m = next.(model)
+		if m.adder.step != adderChecking {
+			t.Fatalf("step after enter = %v, want adderChecking", m.adder.step)
+		}
+		for _, msg := range runCmd(cmd) {
+			next, _ := m.Update(msg)
+			m = next.(model)
+		}
+		return m
+	}
+
+	m := submit("known")
+	if m.adder.step != adderConfirm || m.adder.title != "Known Show" || m.adder.err != nil {
+		t.Errorf("adder = step %v, title %q, err %v, want the confirmation with a title preview", m.adder.step, m.adder.title, m.adder.err)
+	}
+
+	m = submit("unknown")
+	if m.adder.step != adderGUID || m.adder.err == nil {
+		t.Errorf("adder = step %v, err %v, want back at the GUID with an error", m.adder.step, m.adder.err)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"mime"
+	"net/http"
+)
+
+// Apple Podcasts requires square JPEG or PNG artwork within these bounds.
+const (
+	minArtworkSize = 1400
+	maxArtworkSize = 3000
+)
+
+// checkArtwork looks up the cover image and warns if it is not a JPEG or
+// PNG. With checkDimensions it also downloads enough of the image to read
+// its size, which costs a full GET per series.
+func checkArtwork(ctx context.Context, coverURL string, checkDimensions bool) []feedIssue {
+	if coverURL == "" {
+		return nil
+	}
+	warn := func(format string, args ...any) []feedIssue {
+		return []feedIssue{{Severity: severityWarning, Message: fmt.Sprintf(format, args...)}}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, coverURL, nil)
+	if err != nil {
+		return warn("artwork URL %s is invalid: %v", coverURL, err)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return warn("failed to check artwork %s: %v", coverURL, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return warn("artwork %s returned status code %d", coverURL, resp.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "image/jpeg" && mediaType != "image/png" {
+		return warn("artwork %s has content type %q, Apple Podcasts requires JPEG or PNG", coverURL, mediaType)
+	}
+
+	if !checkDimensions {
+		return nil
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, coverURL, nil)
+	if err != nil {
+		return warn("artwork URL %s is invalid: %v", coverURL, err)
+	}
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		return warn("failed to download artwork %s: %v", coverURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return warn("artwork %s returned status code %d", coverURL, resp.StatusCode)
+	}
+
+	// DecodeConfig only reads the image header
+	cfg, _, err := image.DecodeConfig(resp.Body)
+	if err != nil {
+		return warn("failed to decode artwork %s: %v", coverURL, err)
+	}
+
+	var issues []feedIssue
+	if cfg.Width != cfg.Height {
+		issues = append(issues, warn("artwork is %dx%d, Apple Podcasts requires a square image", cfg.Width, cfg.Height)...)
+	}
+	if min(cfg.Width, cfg.Height) < minArtworkSize || max(cfg.Width, cfg.Height) > maxArtworkSize {
+		issues = append(issues, warn("artwork is %dx%d, Apple Podcasts requires %d to %d pixels per side", cfg.Width, cfg.Height, minArtworkSize, maxArtworkSize)...)
+	}
+	return issues
+}
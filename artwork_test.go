@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// imageServer serves a PNG of the size in the path, e.g. /1400x1400.png,
+// with the content type given by the "type" query parameter if set.
+func imageServer(t *testing.T, gets *atomic.Int32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var width, height int
+		if _, err := fmt.Sscanf(r.URL.Path, "/%dx%d.png", &width, &height); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		contentType := r.URL.Query().Get("type")
+		if contentType == "" {
+			contentType = "image/png"
+		}
+		w.Header().Set("Content-Type", contentType)
+		if r.Method != http.MethodGet {
+			return
+		}
+		gets.Add(1)
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+			t.Error(err)
+		}
+		w.Write(buf.Bytes())
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCheckArtwork(t *testing.T) {
+	var gets atomic.Int32
+	srv := imageServer(t, &gets)
+
+	tests := []struct {
+		name       string
+		path       string
+		dimensions bool
+		want       []string
+	}{
+		{"valid", "/1400x1400.png", true, nil},
+		{"too small", "/1000x1000.png", true, []string{"1400 to 3000 pixels"}},
+		{"not square", "/1400x1600.png", true, []string{"square"}},
+		{"wrong type", "/1400x1400.png?type=image/gif", true, []string{`"image/gif"`}},
+		{"jpeg with parameters", "/1400x1400.png?type=image/jpeg;+charset=binary", false, nil},
+		{"missing", "/cover.jpg", true, []string{"status code 404"}},
+		{"size not checked", "/1000x1000.png", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := checkArtwork(context.Background(), srv.URL+tt.path, tt.dimensions)
+			if len(issues) != len(tt.want) {
+				t.Fatalf("checkArtwork() = %v, want %d issues", issues, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if issues[i].Severity != severityWarning || !strings.Contains(issues[i].Message, want) {
+					t.Errorf("issue %d = %v, want a warning mentioning %s", i, issues[i], want)
+				}
+			}
+		})
+	}
+
+	gets.Store(0)
+	checkArtwork(context.Background(), srv.URL+"/1400x1400.png", false)
+	if n := gets.Load(); n != 0 {
+		t.Errorf("checkArtwork() without the dimension check downloaded the image %d times", n)
+	}
+}
+
+func TestCheckArtworkDownloadStatus(t *testing.T) {
+	// Some CDNs answer HEAD but refuse the GET with an HTML error page
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		if r.Method == http.MethodGet {
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("<html>Forbidden</html>"))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	issues := checkArtwork(context.Background(), srv.URL+"/cover.png", true)
+	if len(issues) != 1 || !strings.Contains(issues[0].Message, "status code 403") {
+		t.Errorf("checkArtwork() = %v, want the download's status code", issues)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// browserCommand returns the command that opens url in the default browser
+// on the given platform.
+func browserCommand(goos, url string) (string, []string) {
+	switch goos {
+	case "darwin":
+		return "open", []string{url}
+	case "windows":
+		return "rundll32", []string{"url.dll,FileProtocolHandler", url}
+	default:
+		return "xdg-open", []string{url}
+	}
+}
+
+func openInBrowser(url string) error {
+	name, args := browserCommand(runtime.GOOS, url)
+	if _, err := exec.LookPath(name); err != nil {
+		return fmt.Errorf("%s not available: %w", name, err)
+	}
+	return exec.Command(name, args...).Start()
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBrowserCommand(t *testing.T) {
+	const url = "https://feeds.example.com/show.rss"
+	tests := []struct {
+		goos     string
+		wantName string
+		wantArgs []string
+	}{
+		{"darwin", "open", []string{url}},
+		{"windows", "rundll32", []string{"url.dll,FileProtocolHandler", url}},
+		{"linux", "xdg-open", []string{url}},
+		{"freebsd", "xdg-open", []string{url}},
+	}
+	for _, tt := range tests {
+		name, args := browserCommand(tt.goos, url)
+		if name != tt.wantName || !slices.Equal(args, tt.wantArgs) {
+			t.Errorf("browserCommand(%q) = %s %q, want %s %q", tt.goos, name, args, tt.wantName, tt.wantArgs)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// appleCategories is the Apple Podcasts category taxonomy: each top-level
+// category with its allowed subcategories.
+var appleCategories = map[string][]string{
+	"Arts":                    {"Books", "Design", "Fashion & Beauty", "Food", "Performing Arts", "Visual Arts"},
+	"Business":                {"Careers", "Entrepreneurship", "Investing", "Management", "Marketing", "Non-Profit"},
+	"Comedy":                  {"Comedy Interviews", "Improv", "Stand-Up"},
+	"Education":               {"Courses", "How To", "Language Learning", "Self-Improvement"},
+	"Fiction":                 {"Comedy Fiction", "Drama", "Science Fiction"},
+	"Government":              nil,
+	"History":                 nil,
+	"Health & Fitness":        {"Alternative Health", "Fitness", "Medicine", "Mental Health", "Nutrition", "Sexuality"},
+	"Kids & Family":           {"Education for Kids", "Parenting", "Pets & Animals", "Stories for Kids"},
+	"Leisure":                 {"Animation & Manga", "Automotive", "Aviation", "Crafts", "Games", "Hobbies", "Home & Garden", "Video Games"},
+	"Music":                   {"Music Commentary", "Music History", "Music Interviews"},
+	"News":                    {"Business News", "Daily News", "Entertainment News", "News Commentary", "Politics", "Sports News", "Tech News"},
+	"Religion & Spirituality": {"Buddhism", "Christianity", "Hinduism", "Islam", "Judaism", "Religion", "Spirituality"},
+	"Science":                 {"Astronomy", "Chemistry", "Earth Sciences", "Life Sciences", "Mathematics", "Natural Sciences", "Nature", "Physics", "Social Sciences"},
+	"Society & Culture":       {"Documentary", "Personal Journals", "Philosophy", "Places & Travel", "Relationships"},
+	"Sports":                  {"Baseball", "Basketball", "Cricket", "Fantasy Sports", "Football", "Golf", "Hockey", "Rugby", "Running", "Soccer", "Swimming", "Tennis", "Volleyball", "Wilderness", "Wrestling"},
+	"Technology":              nil,
+	"True Crime":              nil,
+	"TV & Film":               {"After Shows", "Film History", "Film Interviews", "Film Reviews", "TV Reviews"},
+}
+
+// parseAppleCategory splits a "Category" or "Category/Subcategory" target
+// and checks it against the Apple taxonomy.
+func parseAppleCategory(target string) (Category, error) {
+	parent, sub, hasSub := strings.Cut(target, "/")
+	parent, sub = strings.TrimSpace(parent), strings.TrimSpace(sub)
+
+	subs, ok := appleCategories[parent]
+	if !ok {
+		return Category{}, fmt.Errorf("%q is not an Apple Podcasts category", parent)
+	}
+	if !hasSub {
+		return Category{Text: parent}, nil
+	}
+	for _, s := range subs {
+		if s == sub {
+			return Category{Text: parent, Subcategory: &Category{Text: sub}}, nil
+		}
+	}
+	return Category{}, fmt.Errorf("%q is not a subcategory of %q in Apple Podcasts", sub, parent)
+}
+
+// mapCategories translates API categories through mapping. Without a
+// mapping the categories pass through unchanged; with one, unmapped
+// categories are dropped unless passUnmapped is set. Duplicates are removed.
+func mapCategories(categories []string, mapping map[string]string, passUnmapped bool) []Category {
+	var result []Category
+	seen := make(map[string]bool)
+	add := func(c Category) {
+		key := c.Text
+		if c.Subcategory != nil {
+			key += "/" + c.Subcategory.Text
+		}
+		if !seen[key] {
+			seen[key] = true
+			result = append(result, c)
+		}
+	}
+
+	for _, c := range categories {
+		if c == "" {
+			continue
+		}
+		target, ok := mapping[c]
+		switch {
+		case ok:
+			// Targets are validated on config load
+			if category, err := parseAppleCategory(target); err == nil {
+				add(category)
+			}
+		case len(mapping) == 0 || passUnmapped:
+			add(Category{Text: c})
+		}
+	}
+	return result
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMapCategories(t *testing.T) {
+	mapping := map[string]string{
+		"Uutiset":    "News/Daily News",
+		"Politiikka": "News/Politics",
+		"Urheilu":    "Sports",
+		"Päivä":      "News/Daily News",
+	}
+	daily := Category{Text: "News", Subcategory: &Category{Text: "Daily News"}}
+	politics := Category{Text: "News", Subcategory: &Category{Text: "Politics"}}
+
+	tests := []struct {
+		name         string
+		categories   []string
+		mapping      map[string]string
+		passUnmapped bool
+		want         []Category
+	}{
+		{"no mapping passes through", []string{"Uutiset", ""}, nil, false, []Category{{Text: "Uutiset"}}},
+		{"mapped", []string{"Uutiset", "Politiikka", "Urheilu"}, mapping, false, []Category{daily, politics, {Text: "Sports"}}},
+		{"mapped duplicates collapse", []string{"Uutiset", "Päivä"}, mapping, false, []Category{daily}},
+		{"unmapped dropped", []string{"Uutiset", "Kulttuuri"}, mapping, false, []Category{daily}},
+		{"unmapped passed through", []string{"Uutiset", "Kulttuuri"}, mapping, true, []Category{daily, {Text: "Kulttuuri"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mapCategories(tt.categories, tt.mapping, tt.passUnmapped); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mapCategories() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAppleCategory(t *testing.T) {
+	for _, target := range []string{"News", "News/Daily News", " Sports / Soccer ", "True Crime"} {
+		if _, err := parseAppleCategory(target); err != nil {
+			t.Errorf("parseAppleCategory(%q) error = %v", target, err)
+		}
+	}
+	for _, target := range []string{"Newz", "News/Weather", "news", "True Crime/Podcasts"} {
+		if _, err := parseAppleCategory(target); err == nil {
+			t.Errorf("parseAppleCategory(%q) succeeded, want an error", target)
+		}
+	}
+
+	config := SeriesConfig{Settings: Settings{CategoryMap: map[string]string{"Uutiset": "News/Weather"}}}
+	if err := config.validate(); err == nil {
+		t.Error("validate() accepted a category_map target outside the Apple taxonomy")
+	}
+}
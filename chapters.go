@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+const chaptersContentType = "application/json+chapters"
+
+// chaptersDocument is the Podcast Namespace JSON chapters format.
+type chaptersDocument struct {
+	Version  string    `json:"version"`
+	Chapters []chapter `json:"chapters"`
+}
+
+type chapter struct {
+	StartTime int    `json:"startTime"`
+	EndTime   int    `json:"endTime,omitempty"`
+	Title     string `json:"title,omitempty"`
+}
+
+// buildChapters converts the episode's audio slices into a chapters
+// document. Episodes without slices have no chapters.
+func buildChapters(episode Episode) (string, bool, error) {
+	if len(episode.AudioSlices) == 0 {
+		return "", false, nil
+	}
+
+	doc := chaptersDocument{Version: "1.2.0"}
+	for i, slice := range episode.AudioSlices {
+		doc.Chapters = append(doc.Chapters, chapter{
+			StartTime: int(slice.Start),
+			EndTime:   int(slice.End),
+			Title:     fmt.Sprintf("Part %d", i+1),
+		})
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", false, fmt.Errorf("failed to marshal chapters: %w", err)
+	}
+	return string(data), true, nil
+}
+
+// chaptersS3Path places an episode's chapters file in a chapters/ directory
+// next to the feed. The GUID is escaped without collisions, so distinct
+// episodes never share a file.
+func chaptersS3Path(feedPath, episodeGUID string) string {
+	dir := path.Dir(strings.TrimPrefix(feedPath, "s3://"))
+	return "s3://" + path.Join(dir, "chapters", escapeFilename(episodeGUID)+".json")
+}
+
+// uploadChapters uploads the chapters file of every episode that has one,
+// skipping files that are already up to date. Pass only the episodes that
+// made it into the feed, so nothing is uploaded that the feed never links.
+func uploadChapters(ctx context.Context, s3Client *S3Client, episodes []Episode, feedPath string) error {
+	for _, episode := range episodes {
+		content, ok, err := buildChapters(episode)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if _, err := s3Client.UploadFeedIfChanged(ctx, content, chaptersS3Path(feedPath, episode.GUID), chaptersContentType); err != nil {
+			return fmt.Errorf("failed to upload chapters for %s: %w", episode.GUID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestBuildChapters(t *testing.T) {
+	episode := Episode{AudioSlices: []AudioSlice{
+		{Start: 0, End: 95},
+		{Start: 95, End: 1800},
+	}}
+
+	content, ok, err := buildChapters(episode)
+	if err != nil || !ok {
+		t.Fatalf("buildChapters() = %v, %v; want chapters", ok, err)
+	}
+	var doc chaptersDocument
+	if err := json.Unmarshal([]byte(content), &doc); err != nil {
+		t.Fatalf("chapters are not valid JSON: %v", err)
+	}
+	want := []chapter{
+		{StartTime: 0, EndTime: 95, Title: "Part 1"},
+		{StartTime: 95, EndTime: 1800, Title: "Part 2"},
+	}
+	if doc.Version != "1.2.0" || len(doc.Chapters) != len(want) {
+		t.Fatalf("chapters = %+v, want version 1.2.0 with %d chapters", doc, len(want))
+	}
+	for i := range want {
+		if doc.Chapters[i] != want[i] {
+			t.Errorf("chapter %d = %+v, want %+v", i, doc.Chapters[i], want[i])
+		}
+	}
+
+	if _, ok, _ := buildChapters(Episode{}); ok {
+		t.Error("buildChapters() of an episode without slices returned chapters")
+	}
+}
+
+func TestUploadSeriesChapters(t *testing.T) {
+	settings := apiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {"guid": "show", "title": "Show", "episodes": [
+			{"guid": "past", "title": "Past", "publication_date": "2024-01-01T10:00:00Z",
+			 "audio_url": "https://cdn.example.com/past.mp3", "audio_slices": [{"start": 0, "end": 60}]},
+			{"guid": "future", "title": "Future", "publication_date": "2099-01-01T10:00:00Z",
+			 "audio_url": "https://cdn.example.com/future.mp3", "audio_slices": [{"start": 0, "end": 60}]}
+		]}}`))
+	})
+	settings.Chapters = true
+	series := Series{GUID: "show", S3Path: "s3://bucket/show/feed.rss"}
+	fake := newFakeS3()
+	client := fake.client()
+
+	if result := uploadSeries(context.Background(), client, series, settings); result.Err != nil {
+		t.Fatalf("uploadSeries() error = %v", result.Err)
+	}
+	if _, ok := fake.objects["bucket/show/chapters/past.json"]; !ok {
+		t.Error("chapters of the episode in the feed were not uploaded")
+	}
+	if _, ok := fake.objects["bucket/show/chapters/future.json"]; ok {
+		t.Error("chapters of an episode left out of the feed were uploaded")
+	}
+
+	puts := len(fake.puts)
+	if result := uploadSeries(context.Background(), client, series, settings); result.Err != nil {
+		t.Fatalf("second uploadSeries() error = %v", result.Err)
+	}
+	if len(fake.puts) != puts {
+		t.Errorf("second upload made %d puts, want none for unchanged feed and chapters", len(fake.puts)-puts)
+	}
+}
+
+func TestChaptersS3PathNoCollisions(t *testing.T) {
+	a := chaptersS3Path("s3://bucket/show/feed.rss", "ep/1")
+	b := chaptersS3Path("s3://bucket/show/feed.rss", "ep:1")
+	if a == b {
+		t.Errorf("GUIDs ep/1 and ep:1 share the chapters path %s", a)
+	}
+	if want := "s3://bucket/show/chapters/ep__1.json"; a != want {
+		t.Errorf("chaptersS3Path(ep/1) = %s, want %s", a, want)
+	}
+}
+
+func TestUploadSeriesChaptersOnlyToPrimary(t *testing.T) {
+	settings := apiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {"guid": "show", "title": "Show", "episodes": [
+			{"guid": "ep", "title": "Episode", "publication_date": "2024-01-01T10:00:00Z",
+			 "audio_url": "https://cdn.example.com/ep.mp3", "audio_slices": [{"start": 0, "end": 60}]}
+		]}}`))
+	})
+	settings.Chapters = true
+	series := Series{GUID: "show", S3Path: "s3://bucket/show/feed.rss", MirrorPaths: []string{"s3://mirror/show/feed.rss"}}
+	fake := newFakeS3()
+
+	if result := uploadSeries(context.Background(), fake.client(), series, settings); result.Err != nil {
+		t.Fatalf("uploadSeries() error = %v", result.Err)
+	}
+	if _, ok := fake.objects["bucket/show/chapters/ep.json"]; !ok {
+		t.Error("chapters were not uploaded next to the primary feed")
+	}
+	if _, ok := fake.objects["mirror/show/chapters/ep.json"]; ok {
+		t.Error("chapters were uploaded to the mirror, whose feed never links them")
+	}
+	if _, ok := fake.objects["mirror/show/feed.rss"]; !ok {
+		t.Error("the mirror feed was not uploaded")
+	}
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// jsonResult is the machine-readable form of a seriesResult.
+type jsonResult struct {
+	GUID     string   `json:"guid"`
+	Title    string   `json:"title"`
+	Episodes int      `json:"episodes"`
+	Location string   `json:"location,omitempty"`
+	Success  bool     `json:"success"`
+	Error    string   `json:"error,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// runSummary totals a headless run.
+type runSummary struct {
+	Total         int     `json:"total"`
+	Succeeded     int     `json:"succeeded"`
+	Failed        int     `json:"failed"`
+	Skipped       int     `json:"skipped"`
+	Unchanged     int     `json:"unchanged"`
+	BytesUploaded int64   `json:"bytes_uploaded"`
+	ElapsedSec    float64 `json:"elapsed_seconds"`
+}
+
+func summarize(results []seriesResult, elapsed time.Duration) runSummary {
+	summary := runSummary{Total: len(results), ElapsedSec: elapsed.Seconds()}
+	for _, r := range results {
+		switch {
+		case r.skipped():
+			summary.Skipped++
+		case r.Err != nil:
+			summary.Failed++
+		case r.Location == "" && len(r.Unchanged) > 0:
+			summary.Unchanged++
+		default:
+			summary.Succeeded++
+		}
+		summary.BytesUploaded += r.BytesUploaded
+	}
+	return summary
+}
+
+func (s runSummary) String() string {
+	return fmt.Sprintf("%d series: %d succeeded, %d failed, %d skipped, %d unchanged; %d bytes uploaded in %.1fs",
+		s.Total, s.Succeeded, s.Failed, s.Skipped, s.Unchanged, s.BytesUploaded, s.ElapsedSec)
+}
+
+func newJSONResult(r seriesResult) jsonResult {
+	jr := jsonResult{
+		GUID:     r.GUID,
+		Title:    r.Title,
+		Episodes: r.Episodes,
+		Location: r.Location,
+		Success:  r.Err == nil,
+		Warnings: r.Warnings,
+	}
+	if r.Err != nil {
+		jr.Error = r.Err.Error()
+	}
+	return jr
+}
+
+// selectSeries returns the configured series matching guids, or all of them
+// when guids is empty.
+func selectSeries(all []Series, guids []string) ([]Series, error) {
+	if len(guids) == 0 {
+		return all, nil
+	}
+
+	byGUID := make(map[string]Series, len(all))
+	for _, series := range all {
+		byGUID[series.GUID] = series
+	}
+
+	selected := make([]Series, 0, len(guids))
+	for _, guid := range guids {
+		series, ok := byGUID[guid]
+		if !ok {
+			return nil, fmt.Errorf("series %s is not in the config", guid)
+		}
+		selected = append(selected, series)
+	}
+	return selected, nil
+}
+
+// runHeadless performs command for the selected series without the TUI and
+// returns the process exit status: 0 only if every action succeeded.
+func runHeadless(config *SeriesConfig, command string, guids []string, jsonOutput bool) int {
+	switch command {
+	case "latest":
+		return printLatestEpisodes(config)
+	case "opml":
+		return runOPML(config)
+	}
+
+	series, err := selectSeries(config.Series, guids)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	var action func(Series) seriesResult
+	switch command {
+	case "generate":
+		action = func(s Series) seriesResult {
+			return generateSeries(context.Background(), s, config.Settings)
+		}
+	case "upload":
+		s3Client, err := NewS3Client(context.Background(), config.Settings)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to initialize S3 client: %v\n", err)
+			return 1
+		}
+		action = func(s Series) seriesResult {
+			return uploadSeries(context.Background(), s3Client, s, config.Settings)
+		}
+	case "undo":
+		// Never roll back every feed by accident
+		if len(guids) == 0 {
+			fmt.Fprintln(os.Stderr, "Error: undo requires at least one series GUID")
+			return 2
+		}
+		s3Client, err := NewS3Client(context.Background(), config.Settings)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to initialize S3 client: %v\n", err)
+			return 1
+		}
+		action = func(s Series) seriesResult {
+			return undoUpload(context.Background(), s3Client, s)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown command %q\n", command)
+		return 2
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	status := 0
+	start := time.Now()
+	results := make([]seriesResult, 0, len(series))
+	for _, s := range series {
+		result := action(s)
+		results = append(results, result)
+		if result.Err != nil {
+			status = 1
+		}
+
+		if jsonOutput {
+			if err := encoder.Encode(newJSONResult(result)); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: failed to encode result: %v\n", err)
+				status = 1
+			}
+		} else {
+			fmt.Printf("%s: %s\n", s.GUID, result)
+		}
+	}
+
+	summary := summarize(results, time.Since(start))
+	if jsonOutput {
+		if err := encoder.Encode(struct {
+			Summary runSummary `json:"summary"`
+		}{summary}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to encode summary: %v\n", err)
+			status = 1
+		}
+	} else {
+		fmt.Printf("Summary: %s\n", summary)
+	}
+
+	return status
+}
+
+// printFeed writes the feed for guid to stdout and nothing else, so the
+// output can be piped. Warnings and errors go to stderr.
+func printFeed(config *SeriesConfig, guid string) int {
+	series, err := selectSeries(config.Series, []string{guid})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	result := seriesResult{GUID: guid}
+	_, rssXML, err := buildSeriesFeed(context.Background(), series[0], config.Settings, &result)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+
+	if _, err := io.WriteString(os.Stdout, rssXML); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to write feed: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// s3Check is the outcome of checking one S3 destination.
+type s3Check struct {
+	GUID string
+	Path string
+	Err  error
+}
+
+// checkS3Paths checks every destination of the given series in turn.
+func checkS3Paths(ctx context.Context, s3Client *S3Client, series []Series) []s3Check {
+	var checks []s3Check
+	for _, s := range series {
+		for _, s3Path := range s.destinations() {
+			checks = append(checks, s3Check{GUID: s.GUID, Path: s3Path, Err: s3Client.CheckWritable(ctx, s3Path)})
+		}
+	}
+	return checks
+}
+
+// runS3Check reports per-path write access and returns a non-zero status
+// if any path failed.
+func runS3Check(config *SeriesConfig) int {
+	s3Client, err := NewS3Client(context.Background(), config.Settings)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to initialize S3 client: %v\n", err)
+		return 1
+	}
+
+	status := 0
+	for _, check := range checkS3Paths(context.Background(), s3Client, config.Series) {
+		if check.Err != nil {
+			fmt.Printf("%s: %s: error: %v\n", check.GUID, check.Path, check.Err)
+			status = 1
+		} else {
+			fmt.Printf("%s: %s: OK\n", check.GUID, check.Path)
+		}
+	}
+	return status
+}
+
+// aclCheck is the outcome of checking the ACL of one S3 destination.
+type aclCheck struct {
+	GUID   string
+	Path   string
+	Public bool
+	Err    error
+}
+
+// checkACLs reads the ACL of every destination of the given series in turn.
+func checkACLs(ctx context.Context, s3Client *S3Client, series []Series) []aclCheck {
+	var checks []aclCheck
+	for _, s := range series {
+		for _, s3Path := range s.destinations() {
+			public, err := s3Client.IsPublic(ctx, s3Path)
+			checks = append(checks, aclCheck{GUID: s.GUID, Path: s3Path, Public: public, Err: err})
+		}
+	}
+	return checks
+}
+
+// runACLCheck reports feeds that are not publicly readable, which breaks
+// subscribers, and returns a non-zero status if there are any.
+func runACLCheck(config *SeriesConfig) int {
+	s3Client, err := NewS3Client(context.Background(), config.Settings)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to initialize S3 client: %v\n", err)
+		return 1
+	}
+
+	status := 0
+	for _, check := range checkACLs(context.Background(), s3Client, config.Series) {
+		switch {
+		case check.Err != nil:
+			fmt.Printf("%s: %s: error: %v\n", check.GUID, check.Path, check.Err)
+			status = 1
+		case !check.Public:
+			fmt.Printf("%s: %s: private, expected public-read\n", check.GUID, check.Path)
+			status = 1
+		default:
+			fmt.Printf("%s: %s: public-read OK\n", check.GUID, check.Path)
+		}
+	}
+	return status
+}
+
+// runWatch uploads every series each interval until SIGINT or SIGTERM.
+// Unchanged feeds are skipped as usual, so idle cycles do not write to S3.
+func runWatch(config *SeriesConfig, interval time.Duration) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	s3Client, err := NewS3Client(ctx, config.Settings)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to initialize S3 client: %v\n", err)
+		return 1
+	}
+
+	for cycle := 1; ; cycle++ {
+		start := time.Now()
+		results := watchCycle(ctx, s3Client, config)
+		summary := summarize(results, time.Since(start))
+		slog.Info("watch cycle finished", "cycle", cycle, "summary", summary.String())
+		fmt.Printf("Cycle %d: %s; next in %s\n", cycle, summary, interval)
+
+		select {
+		case <-ctx.Done():
+			fmt.Println("Stopping watch")
+			return 0
+		case <-time.After(interval):
+		}
+	}
+}
+
+// watchCycle uploads every series once, stopping early if ctx is done.
+func watchCycle(ctx context.Context, s3Client *S3Client, config *SeriesConfig) []seriesResult {
+	results := make([]seriesResult, 0, len(config.Series))
+	for _, s := range config.Series {
+		if ctx.Err() != nil {
+			break
+		}
+		result := runAction(func(s Series) seriesResult {
+			return uploadSeries(ctx, s3Client, s, config.Settings)
+		}, s)
+		fmt.Printf("%s: %s\n", s.GUID, result)
+		results = append(results, result)
+	}
+	return results
+}
+
+// runOPML writes an OPML index of all feeds to the output directory and,
+// if opml_s3_path is set, uploads it there.
+func runOPML(config *SeriesConfig) int {
+	titles := seriesTitles(config.Series, config.Settings)
+	opml, err := buildOPML(config.Series, titles, config.Settings, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	outputPath, err := writeFeedFile(config.Settings.OutputDir, opmlFilename, opml, config.Settings.fileMode())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to write OPML file: %v\n", err)
+		return 1
+	}
+	fmt.Printf("OPML written to %s\n", outputPath)
+
+	if config.Settings.OPMLS3Path == "" {
+		return 0
+	}
+	s3Client, err := NewS3Client(context.Background(), config.Settings)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to initialize S3 client: %v\n", err)
+		return 1
+	}
+	if err := s3Client.uploadContent(context.Background(), opml, config.Settings.OPMLS3Path, "text/x-opml"); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("OPML uploaded to %s\n", config.Settings.OPMLS3Path)
+	return 0
+}
+
+func printLatestEpisodes(config *SeriesConfig) int {
+	now := time.Now()
+	rows := fetchLatestEpisodes(context.Background(), config.Series, config.Settings, now)
+	fmt.Print(formatLatestTable(rows, now))
+
+	for _, row := range rows {
+		if row.Err != nil {
+			return 1
+		}
+	}
+	return 0
+}
+
+// checkStale lists feeds whose latest episode is older than the configured
+// threshold and returns a non-zero status if there are any.
+func checkStale(config *SeriesConfig) int {
+	now := time.Now()
+	threshold := config.Settings.staleThreshold()
+
+	status := 0
+	for _, row := range fetchLatestEpisodes(context.Background(), config.Series, config.Settings, now) {
+		switch {
+		case row.Err != nil:
+			fmt.Printf("%s: error: %v\n", row.GUID, row.Err)
+			status = 1
+		case isStale(row.Latest, now, threshold):
+			fmt.Printf("%s: stale, last episode %s (%d days ago)\n", row.Title, row.Latest.Format("Jan 2, 2006"), row.daysSince(now))
+			status = 1
+		}
+	}
+	return status
+}
+
+// runValidate validates the feed for guid and returns a non-zero status if
+// it has any hard errors.
+func runValidate(config *SeriesConfig, guid string) int {
+	series, err := selectSeries(config.Series, []string{guid})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	issues, err := validateSeries(context.Background(), series[0], config.Settings)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	if hasValidationErrors(issues) {
+		return 1
+	}
+	fmt.Printf("%s: feed is valid\n", guid)
+	return 0
+}
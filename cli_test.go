@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestJSONResultSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		result seriesResult
+		want   string
+	}{
+		{
+			"success",
+			seriesResult{GUID: "abc", Title: "Show", Episodes: 12, Location: "out/show.rss"},
+			`{"guid":"abc","title":"Show","episodes":12,"location":"out/show.rss","success":true}`,
+		},
+		{
+			"success with warnings",
+			seriesResult{GUID: "abc", Title: "Show", Episodes: 3, Location: "out/show.rss", Warnings: []string{"skipped 1 episodes without audio"}},
+			`{"guid":"abc","title":"Show","episodes":3,"location":"out/show.rss","success":true,"warnings":["skipped 1 episodes without audio"]}`,
+		},
+		{
+			"failure",
+			seriesResult{GUID: "abc", Err: errors.New("failed to fetch series data: API returned status code 500")},
+			`{"guid":"abc","title":"","episodes":0,"success":false,"error":"failed to fetch series data: API returned status code 500"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(newJSONResult(tt.result))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("JSON = %s\nwant   %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// captureStdout returns what fn writes to os.Stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = orig }()
+
+	done := make(chan []byte)
+	go func() {
+		out, _ := io.ReadAll(r)
+		done <- out
+	}()
+	fn()
+	w.Close()
+	return string(<-done)
+}
+
+func TestPrintFeed(t *testing.T) {
+	settings := apiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/show.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"data": {"guid": "show", "title": "Piped show"}}`))
+	})
+	config := &SeriesConfig{
+		Settings: settings,
+		Series: []Series{
+			{GUID: "show", S3Path: "s3://bucket/show.rss"},
+			{GUID: "gone", S3Path: "s3://bucket/gone.rss"},
+		},
+	}
+
+	var status int
+	out := captureStdout(t, func() { status = printFeed(config, "show") })
+	if status != 0 {
+		t.Errorf("printFeed() status = %d, want 0", status)
+	}
+	if !strings.HasPrefix(out, "<?xml") || !strings.Contains(out, "<title>Piped show</title>") {
+		t.Errorf("stdout = %q, want only the feed", out)
+	}
+
+	out = captureStdout(t, func() { status = printFeed(config, "gone") })
+	if status == 0 {
+		t.Error("printFeed() of a missing series status = 0, want failure")
+	}
+	if out != "" {
+		t.Errorf("stdout on failure = %q, want nothing", out)
+	}
+}
+
+func TestWatchCycle(t *testing.T) {
+	settings := apiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/show.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"data": {"guid": "show", "title": "Watched show", "episodes": [
+			{"guid": "ep-1", "title": "One", "publication_date": "2024-05-30T06:00:00Z", "audio_url": "https://cdn.example.com/1.mp3"}
+		]}}`))
+	})
+	config := &SeriesConfig{
+		Settings: settings,
+		Series: []Series{
+			{GUID: "show", S3Path: "s3://bucket/show.rss"},
+			{GUID: "gone", S3Path: "s3://bucket/gone.rss"},
+		},
+	}
+	fake := newFakeS3()
+
+	var results []seriesResult
+	out := captureStdout(t, func() { results = watchCycle(context.Background(), fake.client(), config) })
+	if got := summarize(results, 0); got.Succeeded != 1 || got.Skipped != 1 {
+		t.Errorf("first cycle = %s, want one upload and one skipped series", got)
+	}
+	if len(fake.puts) != 1 || !strings.Contains(out, "show: ") || !strings.Contains(out, "gone: ") {
+		t.Errorf("first cycle made %d uploads, printed %q", len(fake.puts), out)
+	}
+
+	captureStdout(t, func() { results = watchCycle(context.Background(), fake.client(), config) })
+	if got := summarize(results, 0); got.Unchanged != 1 || got.Succeeded != 0 {
+		t.Errorf("second cycle = %s, want the feed unchanged", got)
+	}
+	if len(fake.puts) != 1 {
+		t.Errorf("unchanged cycle uploaded again: %d uploads", len(fake.puts))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	captureStdout(t, func() { results = watchCycle(ctx, fake.client(), config) })
+	if len(results) != 0 {
+		t.Errorf("cancelled cycle ran %d series, want none", len(results))
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	results := []seriesResult{
+		{GUID: "uploaded", Location: "https://feeds.example.com/a.rss", BytesUploaded: 1000},
+		{GUID: "partly", Location: "https://feeds.example.com/b.rss", Unchanged: []string{"chapters"}, BytesUploaded: 500},
+		{GUID: "unchanged", Unchanged: []string{"s3://bucket/c.rss"}},
+		{GUID: "gone", Err: &SeriesNotFoundError{GUID: "gone"}},
+		{GUID: "broken", Err: errors.New("boom")},
+	}
+
+	got := summarize(results, 1500*time.Millisecond)
+	want := runSummary{Total: 5, Succeeded: 2, Failed: 1, Skipped: 1, Unchanged: 1, BytesUploaded: 1500, ElapsedSec: 1.5}
+	if got != want {
+		t.Errorf("summarize() = %+v, want %+v", got, want)
+	}
+	if s := got.String(); s != "5 series: 2 succeeded, 1 failed, 1 skipped, 1 unchanged; 1500 bytes uploaded in 1.5s" {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestRunHeadlessJSONSummary(t *testing.T) {
+	settings := apiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/show.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"data": {"guid": "show", "title": "Show"}}`))
+	})
+	settings.OutputDir = t.TempDir()
+	config := &SeriesConfig{
+		Settings: settings,
+		Series: []Series{
+			{GUID: "show", S3Path: "s3://bucket/show.rss"},
+			{GUID: "gone", S3Path: "s3://bucket/gone.rss"},
+		},
+	}
+
+	out := captureStdout(t, func() { runHeadless(config, "generate", nil, true) })
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("output has %d lines, want two results and the summary:\n%s", len(lines), out)
+	}
+	var last struct {
+		Summary *runSummary `json:"summary"`
+	}
+	if err := json.Unmarshal([]byte(lines[2]), &last); err != nil || last.Summary == nil {
+		t.Fatalf("last line %q is not a summary: %v", lines[2], err)
+	}
+	if s := last.Summary; s.Total != 2 || s.Succeeded != 1 || s.Skipped != 1 {
+		t.Errorf("summary = %+v, want 2 total, 1 succeeded, 1 skipped", *s)
+	}
+}
+
+func TestCheckACLs(t *testing.T) {
+	fake := newFakeS3()
+	fake.objects["bucket/public.rss"] = fakeObject{grants: []types.Grant{publicReadGrant()}}
+	fake.objects["bucket/private.rss"] = fakeObject{}
+	fake.objects["mirror/public.rss"] = fakeObject{grants: []types.Grant{publicReadGrant()}}
+	series := []Series{
+		{GUID: "public", S3Path: "s3://bucket/public.rss", MirrorPaths: []string{"s3://mirror/public.rss"}},
+		{GUID: "private", S3Path: "s3://bucket/private.rss"},
+		{GUID: "missing", S3Path: "s3://bucket/missing.rss"},
+	}
+
+	checks := checkACLs(context.Background(), fake.client(), series)
+	var got []string
+	for _, c := range checks {
+		status := fmt.Sprintf("public=%t", c.Public)
+		if c.Err != nil {
+			status = "error"
+		}
+		got = append(got, c.GUID+" "+c.Path+" "+status)
+	}
+	want := []string{
+		"public s3://bucket/public.rss public=true",
+		"public s3://mirror/public.rss public=true",
+		"private s3://bucket/private.rss public=false",
+		"missing s3://bucket/missing.rss error",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("checkACLs() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+)
+
+const defaultConfigPath = "series.toml"
+
+// resolveConfigPath picks the config file: the --config flag wins over the
+// SUMPPI_CONFIG environment variable, which wins over the default.
+func resolveConfigPath(flagPath string) string {
+	if flagPath != "" {
+		return flagPath
+	}
+	if envPath := os.Getenv("SUMPPI_CONFIG"); envPath != "" {
+		return envPath
+	}
+	return defaultConfigPath
+}
+
+// ConfigNotFoundError means the resolved config file does not exist.
+type ConfigNotFoundError struct {
+	Path     string
+	FlagPath string
+}
+
+func (e *ConfigNotFoundError) Error() string {
+	return fmt.Sprintf("config file %s not found (checked --config %q, SUMPPI_CONFIG %q, default %q)",
+		e.Path, e.FlagPath, os.Getenv("SUMPPI_CONFIG"), defaultConfigPath)
+}
+
+func loadConfig(flagPath string) (*SeriesConfig, error) {
+	configPath := resolveConfigPath(flagPath)
+	if _, err := os.Stat(configPath); errors.Is(err, os.ErrNotExist) {
+		return nil, &ConfigNotFoundError{Path: configPath, FlagPath: flagPath}
+	}
+
+	var config SeriesConfig
+	if _, err := toml.DecodeFile(configPath, &config); err != nil {
+		return nil, fmt.Errorf("failed to decode config file: %w", err)
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &config, nil
+}
+
+// addedSeriesFile is the file in a config directory that series added from
+// the TUI are written to.
+const addedSeriesFile = "added.toml"
+
+// loadConfigDir merges every *.toml file in dir into one config. Series from
+// all files are combined in file name order; at most one file may hold the
+// [settings] table, and a GUID may only be configured once.
+func loadConfigDir(dir string) (*SeriesConfig, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.toml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list config directory: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no *.toml files found in config directory %s", dir)
+	}
+
+	var merged SeriesConfig
+	settingsFile := ""
+	seriesFiles := make(map[string]string)
+	for _, file := range files {
+		var config SeriesConfig
+		meta, err := toml.DecodeFile(file, &config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode config file %s: %w", file, err)
+		}
+
+		if meta.IsDefined("settings") {
+			if settingsFile != "" {
+				return nil, fmt.Errorf("[settings] is defined in both %s and %s", settingsFile, file)
+			}
+			merged.Settings = config.Settings
+			settingsFile = file
+		}
+
+		for _, series := range config.Series {
+			if first, ok := seriesFiles[series.GUID]; ok {
+				return nil, fmt.Errorf("series %s is configured in both %s and %s", series.GUID, first, file)
+			}
+			seriesFiles[series.GUID] = file
+			merged.Series = append(merged.Series, series)
+		}
+	}
+
+	if err := merged.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &merged, nil
+}
+
+// configTemplate is offered when no config file exists.
+const configTemplate = `# sumppi configuration. Only guid and s3_path are required per series;
+# every setting below is optional and shown with its default or an example.
+
+[settings]
+# Timezone for the "Available from" line in episode descriptions.
+timezone = "Europe/Helsinki"
+# Convert pubDate values into this timezone instead of the API's offset.
+# pubdate_timezone = "Europe/Helsinki"
+# Directory for locally generated feeds.
+output_dir = "."
+# Gzip feeds on upload.
+compress_upload = false
+# Upload even when the stored feed is identical.
+force_upload = false
+# Series processed at once by generate/upload all.
+batch_concurrency = 4
+# Attempts for an upload S3 throttles.
+upload_max_attempts = 3
+# Days without a new episode before a feed is flagged as stale.
+stale_days = 30
+# Public URLs: a CDN per "bucket" or "bucket/prefix", or one base URL.
+# cdn_urls = { "my-bucket" = "https://d123.cloudfront.net" }
+# public_base_url = "https://feeds.example.com"
+# Tag filters; exclude wins over include.
+# include_tags = ["news"]
+# exclude_tags = ["trailer"]
+# Added to every episode title.
+# title_prefix = "[Archive]"
+# Validation checks for the cover image.
+# check_artwork = true
+# check_artwork_dimensions = false
+# Copy the cover image into the bucket next to each feed on upload.
+# mirror_cover = false
+
+[[series]]
+guid = "00000000-0000-0000-0000-000000000000"
+s3_path = "s3://my-bucket/podcasts/example.xml"
+# mirror_s3_paths = ["s3://backup-bucket/podcasts/example.xml"]
+# itunes_type = "serial"
+# explicit = false
+# title_override = "Example Podcast"
+`
+
+// writeConfigTemplate creates path with the template, refusing to replace
+// an existing file.
+func writeConfigTemplate(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create config file: %w", err)
+	}
+	if _, err := f.WriteString(configTemplate); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestValidateSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings Settings
+		wantErr  string
+	}{
+		{"defaults", Settings{}, ""},
+		{"invalid_dates skip", Settings{InvalidDates: "skip"}, ""},
+		{"invalid_dates omit", Settings{InvalidDates: "omit"}, ""},
+		{"invalid_dates unknown", Settings{InvalidDates: "drop"}, "invalid_dates"},
+		{"guid_source rss_guid", Settings{GUIDSource: "rss_guid"}, ""},
+		{"guid_source guid", Settings{GUIDSource: "guid"}, ""},
+		{"guid_source unknown", Settings{GUIDSource: "rssguid"}, "guid_source"},
+		{"pubdate_timezone", Settings{PubDateTimezone: "Europe/Helsinki"}, ""},
+		{"pubdate_timezone unknown", Settings{PubDateTimezone: "Europe/Turku"}, "pubdate_timezone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := SeriesConfig{Settings: tt.settings}
+			err := config.validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validate() error = %v, want one mentioning %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResolveConfigPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		flagPath string
+		env      string
+		want     string
+	}{
+		{"default", "", "", defaultConfigPath},
+		{"env over default", "", "env.toml", "env.toml"},
+		{"flag over env", "flag.toml", "env.toml", "flag.toml"},
+		{"flag over default", "flag.toml", "", "flag.toml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SUMPPI_CONFIG", tt.env)
+			if got := resolveConfigPath(tt.flagPath); got != tt.want {
+				t.Errorf("resolveConfigPath(%q) = %q, want %q", tt.flagPath, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigNotFound(t *testing.T) {
+	t.Setenv("SUMPPI_CONFIG", "env.toml")
+	missing := filepath.Join(t.TempDir(), "missing.toml")
+
+	_, err := loadConfig(missing)
+	var notFound *ConfigNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("loadConfig() error = %v, want ConfigNotFoundError", err)
+	}
+	for _, candidate := range []string{missing, "env.toml", defaultConfigPath} {
+		if !strings.Contains(err.Error(), candidate) {
+			t.Errorf("error %q does not name %q", err, candidate)
+		}
+	}
+}
+
+func TestValidateITunesType(t *testing.T) {
+	for _, itunesType := range []string{"", "episodic", "serial"} {
+		config := SeriesConfig{Series: []Series{{GUID: "abc", S3Path: "s3://bucket/show.rss", ITunesType: itunesType}}}
+		if err := config.validate(); err != nil {
+			t.Errorf("validate() with itunes_type %q error = %v", itunesType, err)
+		}
+	}
+
+	for _, itunesType := range []string{"Serial", "full", "chronological"} {
+		config := SeriesConfig{Series: []Series{{GUID: "abc", S3Path: "s3://bucket/show.rss", ITunesType: itunesType}}}
+		err := config.validate()
+		if err == nil || !strings.Contains(err.Error(), "itunes_type") {
+			t.Errorf("validate() with itunes_type %q error = %v, want an itunes_type error", itunesType, err)
+		}
+	}
+}
+
+func TestValidateSchedule(t *testing.T) {
+	series := Series{TTL: 60, SkipHours: []int{0, 23}, SkipDays: []string{"saturday", "SUNDAY"}}
+	if err := series.validateSchedule(); err != nil {
+		t.Fatalf("validateSchedule() error = %v", err)
+	}
+	if want := []string{"Saturday", "Sunday"}; !slices.Equal(series.SkipDays, want) {
+		t.Errorf("SkipDays = %q, want %q", series.SkipDays, want)
+	}
+
+	tests := []struct {
+		series Series
+		want   string
+	}{
+		{Series{TTL: -1}, "ttl"},
+		{Series{SkipHours: []int{24}}, "skip_hours"},
+		{Series{SkipHours: []int{-1}}, "skip_hours"},
+		{Series{SkipDays: []string{"Sat"}}, "skip_days"},
+		{Series{SkipDays: []string{"Funday"}}, "skip_days"},
+	}
+	for _, tt := range tests {
+		err := tt.series.validateSchedule()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("validateSchedule(%+v) error = %v, want a %s error", tt.series, err, tt.want)
+		}
+	}
+}
+
+func TestValidateLockedOwner(t *testing.T) {
+	tests := []struct {
+		locked bool
+		owner  string
+		valid  bool
+	}{
+		{false, "", true},
+		{true, "owner@example.com", true},
+		{true, "", false},
+		{true, "not an email", false},
+		{true, "Owner <owner@example.com>", false},
+	}
+	for _, tt := range tests {
+		config := SeriesConfig{Series: []Series{{GUID: "abc", S3Path: "s3://bucket/show.rss", Locked: tt.locked, LockedOwner: tt.owner}}}
+		err := config.validate()
+		if tt.valid && err != nil {
+			t.Errorf("validate() with locked %t, owner %q error = %v", tt.locked, tt.owner, err)
+		}
+		if !tt.valid && (err == nil || !strings.Contains(err.Error(), "locked_owner")) {
+			t.Errorf("validate() with locked %t, owner %q error = %v, want a locked_owner error", tt.locked, tt.owner, err)
+		}
+	}
+}
+
+// writeConfigFiles writes name → content files into a new directory.
+func writeConfigFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadConfigDir(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"a-publisher.toml": `
+[settings]
+output_dir = "feeds"
+
+[[series]]
+guid = "one"
+s3_path = "s3://bucket/one.rss"
+`,
+		"b-publisher.toml": `
+[[series]]
+guid = "two"
+s3_path = "s3://bucket/two.rss"
+
+[[series]]
+guid = "three"
+s3_path = "s3://bucket/three.rss"
+`,
+		"notes.txt": "not a config file",
+	})
+
+	config, err := loadConfigDir(dir)
+	if err != nil {
+		t.Fatalf("loadConfigDir() error = %v", err)
+	}
+	var guids []string
+	for _, series := range config.Series {
+		guids = append(guids, series.GUID)
+	}
+	if strings.Join(guids, ",") != "one,two,three" {
+		t.Errorf("series = %v, want one, two, three in file order", guids)
+	}
+	if config.Settings.OutputDir != "feeds" {
+		t.Errorf("OutputDir = %q, want the settings from a-publisher.toml", config.Settings.OutputDir)
+	}
+}
+
+func TestLoadConfigDirDuplicateGUID(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"a.toml": "[[series]]\nguid = \"same\"\ns3_path = \"s3://bucket/a.rss\"\n",
+		"b.toml": "[[series]]\nguid = \"same\"\ns3_path = \"s3://bucket/b.rss\"\n",
+	})
+
+	_, err := loadConfigDir(dir)
+	if err == nil {
+		t.Fatal("loadConfigDir() succeeded, want a duplicate GUID error")
+	}
+	for _, want := range []string{"same", "a.toml", "b.toml"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestConfigTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "series.toml")
+	if err := writeConfigTemplate(path); err != nil {
+		t.Fatalf("writeConfigTemplate() error = %v", err)
+	}
+	config, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("the template is not a valid config: %v", err)
+	}
+	if len(config.Series) != 1 {
+		t.Errorf("template has %d series, want one example", len(config.Series))
+	}
+
+	if err := writeConfigTemplate(path); err == nil {
+		t.Error("writeConfigTemplate() overwrote an existing file")
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// maxCoverSize caps how much of a cover image is downloaded for mirroring.
+const maxCoverSize = 20 << 20
+
+// coverExtensions fixes the extension of the formats directories accept;
+// mime.ExtensionsByType lists several for JPEG in an order that varies.
+var coverExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+// coverS3Path places the mirrored cover next to the feed, named after it so
+// feeds sharing a directory do not overwrite each other's artwork, e.g.
+// s3://bucket/a/feed.rss becomes s3://bucket/a/feed-cover.jpg. The
+// extension follows the downloaded content type, not the source URL.
+func coverS3Path(feedPath, contentType string) string {
+	feed := strings.TrimPrefix(feedPath, "s3://")
+	base := strings.TrimSuffix(feed, path.Ext(feed))
+
+	ext, ok := coverExtensions[contentType]
+	if !ok {
+		if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+	return "s3://" + base + "-cover" + ext
+}
+
+// mirrorCover copies the series cover into the bucket of feedPath and points
+// seriesData at the copy. It returns the number of bytes uploaded; on error
+// seriesData keeps the original URL.
+func mirrorCover(ctx context.Context, s3Client *S3Client, seriesData *SeriesData, feedPath string, settings Settings) (int64, error) {
+	if seriesData.CoverURL == "" {
+		return 0, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, seriesData.CoverURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cover URL: %w", err)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to download cover: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("failed to download cover: status code %d", resp.StatusCode)
+	}
+	image, err := io.ReadAll(io.LimitReader(resp.Body, maxCoverSize+1))
+	if err != nil {
+		return 0, fmt.Errorf("failed to download cover: %w", err)
+	}
+	if len(image) > maxCoverSize {
+		return 0, fmt.Errorf("cover is larger than %d bytes", maxCoverSize)
+	}
+
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !strings.HasPrefix(contentType, "image/") {
+		return 0, fmt.Errorf("cover has content type %q, expected an image", contentType)
+	}
+
+	coverPath := coverS3Path(feedPath, contentType)
+	mirroredURL, err := generateS3URL(coverPath, settings)
+	if err != nil {
+		return 0, err
+	}
+	changed, err := s3Client.UploadAssetIfChanged(ctx, string(image), coverPath, contentType)
+	if err != nil {
+		return 0, fmt.Errorf("failed to upload cover to %s: %w", coverPath, err)
+	}
+
+	seriesData.CoverURL = mirroredURL
+	if !changed {
+		return 0, nil
+	}
+	return int64(len(image)), nil
+}
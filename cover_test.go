@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+func TestCoverS3Path(t *testing.T) {
+	tests := []struct {
+		feedPath, contentType, want string
+	}{
+		{"s3://bucket/a/feed.rss", "image/png", "s3://bucket/a/feed-cover.png"},
+		{"s3://bucket/a/feed.rss", "image/jpeg", "s3://bucket/a/feed-cover.jpg"},
+		{"s3://bucket/feed.json", "image/x-unknown", "s3://bucket/feed-cover"},
+		{"s3://bucket/feed", "image/png", "s3://bucket/feed-cover.png"},
+	}
+	for _, tt := range tests {
+		if got := coverS3Path(tt.feedPath, tt.contentType); got != tt.want {
+			t.Errorf("coverS3Path(%q, %q) = %q, want %q", tt.feedPath, tt.contentType, got, tt.want)
+		}
+	}
+}
+
+func TestMirrorCover(t *testing.T) {
+	var gets atomic.Int32
+	srv := imageServer(t, &gets)
+	settings := Settings{PublicBaseURL: "https://feeds.example.com"}
+
+	fake := newFakeS3()
+	// The extension comes from the content type, not the URL
+	seriesData := &SeriesData{CoverURL: srv.URL + "/100x100.png?type=image/jpeg"}
+	n, err := mirrorCover(context.Background(), fake.client(), seriesData, "s3://bucket/a/feed.rss", settings)
+	if err != nil {
+		t.Fatalf("mirrorCover() error = %v", err)
+	}
+	if seriesData.CoverURL != "https://feeds.example.com/a/feed-cover.jpg" {
+		t.Errorf("CoverURL = %q, want the mirrored URL", seriesData.CoverURL)
+	}
+	obj, ok := fake.objects["bucket/a/feed-cover.jpg"]
+	if !ok || int64(len(obj.body)) != n || n == 0 {
+		t.Errorf("mirrored object = %v (%d bytes reported), want the downloaded image", ok, n)
+	}
+}
+
+func TestMirrorCoverUploadSettings(t *testing.T) {
+	var gets atomic.Int32
+	srv := imageServer(t, &gets)
+
+	fake := newFakeS3()
+	client := fake.client()
+	client.compress = true
+	client.sse = "aws:kms"
+	client.kmsKeyID = "key-1"
+	seriesData := &SeriesData{CoverURL: srv.URL + "/10x10.png"}
+	if _, err := mirrorCover(context.Background(), client, seriesData, "s3://bucket/feed.rss", Settings{}); err != nil {
+		t.Fatalf("mirrorCover() error = %v", err)
+	}
+
+	if len(fake.puts) != 1 {
+		t.Fatalf("PutObject calls = %d, want 1", len(fake.puts))
+	}
+	put := fake.puts[0]
+	if put.ContentEncoding != nil {
+		t.Errorf("cover ContentEncoding = %q, want none", aws.ToString(put.ContentEncoding))
+	}
+	if put.ServerSideEncryption != types.ServerSideEncryptionAes256 || put.SSEKMSKeyId != nil {
+		t.Errorf("cover encryption = %q with KMS key %q, want AES256 only", put.ServerSideEncryption, aws.ToString(put.SSEKMSKeyId))
+	}
+	if aws.ToString(put.ContentType) != "image/png" {
+		t.Errorf("cover ContentType = %q, want image/png", aws.ToString(put.ContentType))
+	}
+}
+
+func TestMirrorCoverFallback(t *testing.T) {
+	var gets atomic.Int32
+	srv := imageServer(t, &gets)
+	settings := Settings{PublicBaseURL: "https://feeds.example.com"}
+
+	tests := []struct {
+		name     string
+		coverURL string
+		putErr   error
+	}{
+		{"download fails", srv.URL + "/missing.png", nil},
+		{"not an image", srv.URL + "/10x10.png?type=text/html", nil},
+		{"upload fails", srv.URL + "/10x10.png", &smithy.GenericAPIError{Code: "AccessDenied"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3()
+			if tt.putErr != nil {
+				fake.putErrs = []error{tt.putErr}
+			}
+			seriesData := &SeriesData{CoverURL: tt.coverURL}
+			n, err := mirrorCover(context.Background(), fake.client(), seriesData, "s3://bucket/feed.rss", settings)
+			if err == nil {
+				t.Fatal("mirrorCover() succeeded, want an error")
+			}
+			if seriesData.CoverURL != tt.coverURL || n != 0 {
+				t.Errorf("CoverURL = %q, bytes = %d, want the original URL and nothing uploaded", seriesData.CoverURL, n)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// feedItemSummary identifies an item when comparing two feeds.
+type feedItemSummary struct {
+	Title string `xml:"title"`
+	GUID  string `xml:"guid"`
+}
+
+func parseFeedItems(document string) ([]feedItemSummary, error) {
+	var feed struct {
+		Items []feedItemSummary `xml:"channel>item"`
+	}
+	if err := xml.Unmarshal([]byte(document), &feed); err != nil {
+		return nil, fmt.Errorf("failed to parse feed: %w", err)
+	}
+	return feed.Items, nil
+}
+
+// diffFeeds lists items added to and removed from oldXML by newXML, keyed
+// by GUID, followed by a count of unchanged items.
+func diffFeeds(oldXML, newXML string) ([]string, error) {
+	oldItems, err := parseFeedItems(oldXML)
+	if err != nil {
+		return nil, fmt.Errorf("existing feed: %w", err)
+	}
+	newItems, err := parseFeedItems(newXML)
+	if err != nil {
+		return nil, fmt.Errorf("new feed: %w", err)
+	}
+
+	oldGUIDs := make(map[string]bool, len(oldItems))
+	for _, item := range oldItems {
+		oldGUIDs[item.GUID] = true
+	}
+	newGUIDs := make(map[string]bool, len(newItems))
+	for _, item := range newItems {
+		newGUIDs[item.GUID] = true
+	}
+
+	var lines []string
+	unchanged := 0
+	for _, item := range newItems {
+		if oldGUIDs[item.GUID] {
+			unchanged++
+			continue
+		}
+		lines = append(lines, "+ "+item.Title)
+	}
+	for _, item := range oldItems {
+		if !newGUIDs[item.GUID] {
+			lines = append(lines, "- "+item.Title)
+		}
+	}
+	lines = append(lines, fmt.Sprintf("  %d unchanged items", unchanged))
+
+	return lines, nil
+}
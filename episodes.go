@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// episodeRow is one row of the episode listing.
+type episodeRow struct {
+	GUID  string `json:"guid"`
+	Title string `json:"title"`
+	// Published is nil when the API date cannot be parsed
+	Published   *time.Time `json:"published"`
+	DurationSec int        `json:"duration_seconds"`
+	AudioBytes  int64      `json:"audio_bytes"`
+}
+
+// episodeRows lists episodes newest first, undated ones last.
+func episodeRows(episodes []Episode) []episodeRow {
+	sorted := sortEpisodes(episodes, sortNewestFirst)
+	rows := make([]episodeRow, len(sorted))
+	for i, episode := range sorted {
+		rows[i] = episodeRow{
+			GUID:        episode.GUID,
+			Title:       episode.Title,
+			DurationSec: episodeDuration(episode),
+			AudioBytes:  int64(episode.AudioLength),
+		}
+		if t, err := parsePublicationDate(episode.PublicationDate); err == nil {
+			rows[i].Published = &t
+		}
+	}
+	return rows
+}
+
+func formatEpisodesTable(rows []episodeRow) string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TITLE\tPUBLISHED\tDURATION\tAUDIO BYTES")
+	for _, row := range rows {
+		published := "-"
+		if row.Published != nil {
+			published = row.Published.Format("Jan 2, 2006 15:04")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", row.Title, published, formatDuration(row.DurationSec), row.AudioBytes)
+	}
+	w.Flush()
+	return sb.String()
+}
+
+// printEpisodes lists the episodes of guid without generating a feed. The
+// series does not have to be configured.
+func printEpisodes(config *SeriesConfig, guid string, jsonOutput bool) int {
+	seriesData, err := fetchSeriesData(context.Background(), guid, config.Settings)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	rows := episodeRows(seriesData.Episodes)
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, row := range rows {
+			if err := encoder.Encode(row); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+		}
+		return 0
+	}
+	fmt.Print(formatEpisodesTable(rows))
+	return 0
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFormatEpisodesTable(t *testing.T) {
+	rows := episodeRows(loadSeriesFixture(t).Episodes)
+
+	want := `TITLE                  PUBLISHED           DURATION  AUDIO BYTES
+Next month             Jul 1, 2024 06:00   0:00      0
+Thursday & the budget  May 30, 2024 06:00  10:15     9840000
+Wednesday              May 29, 2024 06:00  9:00      8640000
+Sample only            May 28, 2024 06:00  0:00      0
+Broken date            -                   0:00      0
+`
+	if got := formatEpisodesTable(rows); got != want {
+		t.Errorf("formatEpisodesTable() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPrintEpisodesJSON(t *testing.T) {
+	fixture, err := os.ReadFile("testdata/series.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := apiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": ` + string(fixture) + `}`))
+	})
+
+	var status int
+	out := captureStdout(t, func() { status = printEpisodes(&SeriesConfig{Settings: settings}, "series-1", true) })
+	if status != 0 {
+		t.Fatalf("printEpisodes() status = %d", status)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("got %d JSON lines, want one per episode:\n%s", len(lines), out)
+	}
+	var second, last map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &second); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(lines[4]), &last); err != nil {
+		t.Fatal(err)
+	}
+	if second["guid"] != "ep-3" || second["published"] != "2024-05-30T06:00:00Z" || second["duration_seconds"] != 615.0 || second["audio_bytes"] != 9840000.0 {
+		t.Errorf("second row = %v", second)
+	}
+	if last["guid"] != "ep-bad-date" || last["published"] != nil {
+		t.Errorf("last row = %v, want the undated episode with a null date", last)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ExtraElement is a config-defined channel element for tags sumppi does not
+// know about, such as a directory's own namespace.
+type ExtraElement struct {
+	// Name is the element name, optionally prefixed, e.g. "spotify:limit".
+	Name string `toml:"name"`
+	// Namespace is the URI declared for the name's prefix. It can be left
+	// out for the prefixes sumppi declares itself.
+	Namespace string `toml:"namespace"`
+	Value     string `toml:"value"`
+	// CDATA wraps the value in a CDATA section instead of escaping it.
+	CDATA bool `toml:"cdata"`
+}
+
+// xmlNamePattern accepts an unprefixed or prefixed XML name, restricted to
+// ASCII so the element always serializes as configured.
+var xmlNamePattern = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_.-]*:)?[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// rawElement marshals an ExtraElement; only one of Text and CDATA is set.
+type rawElement struct {
+	XMLName xml.Name
+	Text    string `xml:",chardata"`
+	CDATA   string `xml:",cdata"`
+}
+
+// validateExtraElements checks element names and that each prefix maps to
+// exactly one namespace, built-in prefixes to their usual one.
+func validateExtraElements(elements []ExtraElement) error {
+	declared := make(map[string]string)
+	for _, e := range elements {
+		if !xmlNamePattern.MatchString(e.Name) {
+			return fmt.Errorf("extra element name %q is not a valid XML name", e.Name)
+		}
+		prefix, _, ok := strings.Cut(e.Name, ":")
+		if !ok {
+			if e.Namespace != "" {
+				return fmt.Errorf("extra element %s has a namespace but no prefix", e.Name)
+			}
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(prefix), "xml") {
+			return fmt.Errorf("extra element %s uses the reserved prefix %q", e.Name, prefix)
+		}
+
+		namespace := e.Namespace
+		if builtin, ok := builtinNamespaces[prefix]; ok {
+			if namespace != "" && namespace != builtin {
+				return fmt.Errorf("extra element %s: prefix %q is already bound to %s", e.Name, prefix, builtin)
+			}
+			continue
+		}
+		if namespace == "" {
+			return fmt.Errorf("extra element %s needs a namespace for prefix %q", e.Name, prefix)
+		}
+		if previous, ok := declared[prefix]; ok && previous != namespace {
+			return fmt.Errorf("extra element %s: prefix %q is bound to both %s and %s", e.Name, prefix, previous, namespace)
+		}
+		declared[prefix] = namespace
+	}
+	return nil
+}
+
+// namespace returns the prefix of the element name and the URI it is bound
+// to, or false for an unprefixed name.
+func (e ExtraElement) namespace() (prefix, uri string, ok bool) {
+	prefix, _, ok = strings.Cut(e.Name, ":")
+	if !ok {
+		return "", "", false
+	}
+	if builtin, isBuiltin := builtinNamespaces[prefix]; isBuiltin {
+		return prefix, builtin, true
+	}
+	return prefix, e.Namespace, true
+}
+
+func rawElements(elements []ExtraElement) []rawElement {
+	raw := make([]rawElement, len(elements))
+	for i, e := range elements {
+		raw[i].XMLName = xml.Name{Local: e.Name}
+		if e.CDATA {
+			raw[i].CDATA = e.Value
+		} else {
+			raw[i].Text = e.Value
+		}
+	}
+	return raw
+}
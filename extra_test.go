@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestExtraElementsEmission(t *testing.T) {
+	series := Series{ExtraElements: []ExtraElement{
+		{Name: "spotify:limit", Namespace: "http://www.spotify.com/ns/rss", Value: "5"},
+		{Name: "itunes:new-tag", Value: "yes"},
+		{Name: "regional", Value: `<b>"Tom & Jerry"</b>`},
+		{Name: "notes", Value: "<p>raw]]>html</p>", CDATA: true},
+	}}
+	if err := validateExtraElements(series.ExtraElements); err != nil {
+		t.Fatalf("validateExtraElements() error = %v", err)
+	}
+
+	rssXML, _, err := generateRSSFeed(&SeriesData{GUID: "series-1", Title: "Show"}, series, false, Settings{}, fixedNow)
+	if err != nil {
+		t.Fatalf("generateRSSFeed() error = %v", err)
+	}
+	for _, want := range []string{
+		`xmlns:spotify="http://www.spotify.com/ns/rss"`,
+		"<spotify:limit>5</spotify:limit>",
+		"<itunes:new-tag>yes</itunes:new-tag>",
+		"<regional>&lt;b&gt;&#34;Tom &amp; Jerry&#34;&lt;/b&gt;</regional>",
+		"<notes><![CDATA[<p>raw]]",
+	} {
+		if !strings.Contains(rssXML, want) {
+			t.Errorf("feed lacks %s:\n%s", want, rssXML)
+		}
+	}
+
+	// Escaped and CDATA values both read back as configured
+	var parsed struct {
+		Channel struct {
+			Regional string `xml:"regional"`
+			Notes    string `xml:"notes"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal([]byte(rssXML), &parsed); err != nil {
+		t.Fatalf("feed does not parse: %v", err)
+	}
+	if parsed.Channel.Regional != series.ExtraElements[2].Value || parsed.Channel.Notes != series.ExtraElements[3].Value {
+		t.Errorf("values read back as %q and %q", parsed.Channel.Regional, parsed.Channel.Notes)
+	}
+}
+
+func TestValidateExtraElements(t *testing.T) {
+	tests := []struct {
+		name     string
+		elements []ExtraElement
+	}{
+		{"invalid name", []ExtraElement{{Name: "bad name"}}},
+		{"namespace without prefix", []ExtraElement{{Name: "plain", Namespace: "http://example.com/ns"}}},
+		{"reserved prefix", []ExtraElement{{Name: "xmlfoo:tag", Namespace: "http://example.com/ns"}}},
+		{"rebinding a built-in prefix", []ExtraElement{{Name: "itunes:tag", Namespace: "http://example.com/ns"}}},
+		{"missing namespace", []ExtraElement{{Name: "spotify:limit"}}},
+		{"conflicting namespaces", []ExtraElement{
+			{Name: "x:one", Namespace: "http://example.com/a"},
+			{Name: "x:two", Namespace: "http://example.com/b"},
+		}},
+	}
+	for _, tt := range tests {
+		if err := validateExtraElements(tt.elements); err == nil {
+			t.Errorf("%s: validateExtraElements() succeeded, want an error", tt.name)
+		}
+	}
+}
@@ -3,22 +3,103 @@ package main
 import (
 	"encoding/xml"
 	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"slices"
+	"sort"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
+// builtinNamespaces are the prefixes sumppi emits elements in itself.
+var builtinNamespaces = map[string]string{
+	"itunes":  "http://www.itunes.com/dtds/podcast-1.0.dtd",
+	"content": "http://purl.org/rss/1.0/modules/content/",
+	"podcast": "https://podcastindex.org/namespace/1.0",
+	"atom":    "http://www.w3.org/2005/Atom",
+}
+
 type RSSFeed struct {
 	XMLName xml.Name `xml:"rss"`
 	Version string   `xml:"version,attr"`
-	Xmlns   string   `xml:"xmlns:itunes,attr"`
-	Channel Channel  `xml:"channel"`
+	// Namespaces holds one xmlns attribute per prefix the feed uses. Code
+	// that emits a prefixed element declares its namespace, so the root
+	// element does not list unused ones.
+	Namespaces []xml.Attr `xml:",any,attr"`
+	Channel    Channel    `xml:"channel"`
+}
+
+// declare adds the namespace declaration for prefix unless it is present.
+func (f *RSSFeed) declare(prefix, uri string) {
+	name := xml.Name{Local: "xmlns:" + prefix}
+	for _, attr := range f.Namespaces {
+		if attr.Name == name {
+			return
+		}
+	}
+	f.Namespaces = append(f.Namespaces, xml.Attr{Name: name, Value: uri})
+}
+
+// declareBuiltin declares one of the namespaces in builtinNamespaces.
+func (f *RSSFeed) declareBuiltin(prefix string) {
+	f.declare(prefix, builtinNamespaces[prefix])
 }
 
 type Channel struct {
-	Title        string `xml:"title"`
-	Description  string `xml:"description"`
-	ITunesAuthor string `xml:"itunes:author"`
-	ITunesImage  Image  `xml:"itunes:image"`
-	Items        []Item `xml:"item"`
+	Title            string       `xml:"title"`
+	Link             string       `xml:"link,omitempty"`
+	AtomLink         *AtomLink    `xml:"atom:link,omitempty"`
+	Description      string       `xml:"description"`
+	Language         string       `xml:"language,omitempty"`
+	PubDate          string       `xml:"pubDate,omitempty"`
+	Copyright        string       `xml:"copyright,omitempty"`
+	TTL              int          `xml:"ttl,omitempty"`
+	SkipHours        *SkipHours   `xml:"skipHours,omitempty"`
+	SkipDays         *SkipDays    `xml:"skipDays,omitempty"`
+	ITunesAuthor     string       `xml:"itunes:author"`
+	ITunesSummary    string       `xml:"itunes:summary,omitempty"`
+	ITunesOwner      *Owner       `xml:"itunes:owner,omitempty"`
+	ITunesExplicit   string       `xml:"itunes:explicit"`
+	ITunesCategories []Category   `xml:"itunes:category"`
+	ITunesImage      Image        `xml:"itunes:image"`
+	ITunesType       string       `xml:"itunes:type"`
+	ITunesKeywords   string       `xml:"itunes:keywords,omitempty"`
+	ITunesNewFeedURL string       `xml:"itunes:new-feed-url,omitempty"`
+	ITunesComplete   string       `xml:"itunes:complete,omitempty"`
+	PodcastLocked    *Locked      `xml:"podcast:locked,omitempty"`
+	PodcastGUID      string       `xml:"podcast:guid,omitempty"`
+	Extra            []rawElement `xml:",any"`
+	Items            []Item       `xml:"item"`
+}
+
+type Locked struct {
+	Owner string `xml:"owner,attr"`
+	Value string `xml:",chardata"`
+}
+
+type SkipHours struct {
+	Hours []int `xml:"hour"`
+}
+
+type SkipDays struct {
+	Days []string `xml:"day"`
+}
+
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type Category struct {
+	Text        string    `xml:"text,attr"`
+	Subcategory *Category `xml:"itunes:category,omitempty"`
+}
+
+type Owner struct {
+	Name string `xml:"itunes:name"`
 }
 
 type Image struct {
@@ -31,20 +112,275 @@ type GUID struct {
 }
 
 type Item struct {
-	Title          string    `xml:"title"`
-	Description    string    `xml:"description"`
-	PubDate        string    `xml:"pubDate"`
-	GUID           GUID      `xml:"guid"`
-	Enclosure      Enclosure `xml:"enclosure"`
-	ITunesDuration string    `xml:"itunes:duration"`
+	Title             string    `xml:"title"`
+	Link              string    `xml:"link,omitempty"`
+	Description       string    `xml:"description"`
+	PubDate           string    `xml:"pubDate,omitempty"`
+	GUID              GUID      `xml:"guid"`
+	Enclosure         Enclosure `xml:"enclosure"`
+	ITunesDuration    string    `xml:"itunes:duration,omitempty"`
+	ITunesImage       *Image    `xml:"itunes:image,omitempty"`
+	ITunesSubtitle    string    `xml:"itunes:subtitle,omitempty"`
+	ITunesSummary     string    `xml:"itunes:summary,omitempty"`
+	ContentEncoded    string    `xml:"content:encoded,omitempty"`
+	ITunesAuthor      string    `xml:"itunes:author,omitempty"`
+	ITunesBlock       string    `xml:"itunes:block,omitempty"`
+	ITunesEpisodeType string    `xml:"itunes:episodeType"`
+	ITunesSeason      int       `xml:"itunes:season,omitempty"`
+	ITunesEpisode     int       `xml:"itunes:episode,omitempty"`
+	ITunesKeywords    string    `xml:"itunes:keywords,omitempty"`
+	PodcastChapters   *Chapters `xml:"podcast:chapters,omitempty"`
+}
+
+type Chapters struct {
+	URL  string `xml:"url,attr"`
+	Type string `xml:"type,attr"`
 }
 
 type Enclosure struct {
 	URL    string `xml:"url,attr"`
-	Length string `xml:"length,attr"`
+	Length string `xml:"length,attr,omitempty"`
 	Type   string `xml:"type,attr"`
 }
 
+// audioPackage returns the URL of the first package in preference that the
+// episode has, and its name.
+func audioPackage(episode Episode, preference []string) (string, string, bool) {
+	for _, name := range preference {
+		if url := episode.AudioPkgs[name]; url != "" {
+			return url, name, true
+		}
+	}
+	return "", "", false
+}
+
+// buildEnclosure uses the first available audio package from preference,
+// then the primary audio, then the audio sample. A zero length is omitted
+// rather than written as "0".
+func buildEnclosure(episode Episode, preference []string) Enclosure {
+	if url, name, ok := audioPackage(episode, preference); ok {
+		slog.Debug("selected audio package", "episode", episode.GUID, "package", name)
+		// AudioLength describes the primary file, so the size is unknown
+		return Enclosure{URL: url, Type: audioMIMEType(url)}
+	}
+
+	enclosure := Enclosure{
+		URL: episode.AudioURL,
+	}
+	if enclosure.URL == "" {
+		enclosure.URL = episode.AudioSample.AudioURL
+	}
+	enclosure.Type = audioMIMEType(enclosure.URL)
+
+	length := episode.AudioLength
+	if length == 0 {
+		length = episode.AudioSample.AudioLength
+	}
+	if length > 0 {
+		enclosure.Length = fmt.Sprintf("%d", length)
+	}
+
+	return enclosure
+}
+
+// hasFullAudio reports whether the episode has its own audio, either the
+// main file or a preferred package. A sample alone does not count.
+func hasFullAudio(episode Episode, preference []string) bool {
+	if episode.AudioURL != "" {
+		return true
+	}
+	_, _, ok := audioPackage(episode, preference)
+	return ok
+}
+
+var audioMIMETypes = map[string]string{
+	".mp3":  "audio/mpeg",
+	".m4a":  "audio/mp4",
+	".mp4":  "audio/mp4",
+	".aac":  "audio/aac",
+	".ogg":  "audio/ogg",
+	".oga":  "audio/ogg",
+	".opus": "audio/opus",
+	".wav":  "audio/wav",
+	".flac": "audio/flac",
+}
+
+// audioMIMEType guesses the MIME type from the URL's file extension,
+// ignoring any query string or fragment. Defaults to audio/mpeg.
+func audioMIMEType(audioURL string) string {
+	if i := strings.IndexAny(audioURL, "?#"); i >= 0 {
+		audioURL = audioURL[:i]
+	}
+	if mimeType, ok := audioMIMETypes[strings.ToLower(path.Ext(audioURL))]; ok {
+		return mimeType
+	}
+	return "audio/mpeg"
+}
+
+// episodeImage prefers the square cover, which suits podcast clients best.
+func episodeImage(episode Episode) *Image {
+	if squareCover := episode.squareCoverURL(); squareCover != "" {
+		return &Image{Href: squareCover}
+	}
+	if episode.CoverURL != "" {
+		return &Image{Href: episode.CoverURL}
+	}
+	return nil
+}
+
+const (
+	defaultSubtitleLength = 255
+	// Apple Podcasts truncates itunes:summary beyond this length.
+	maxSummaryLength = 4000
+)
+
+// channelSummary prefers the configured summary, then a description
+// override, then the HTML description as plain text, then the description.
+func channelSummary(seriesData *SeriesData, series Series) string {
+	summary := series.Summary
+	if summary == "" && series.DescriptionOverride == "" {
+		summary = stripHTML(seriesData.htmlDescription())
+	}
+	if summary == "" {
+		summary = stripHTML(seriesData.Description)
+	}
+	return truncateText(normalizeWhitespace(summary), maxSummaryLength)
+}
+
+func episodeSummary(episode Episode) string {
+	return truncateText(normalizeWhitespace(stripHTML(episode.Description)), maxSummaryLength)
+}
+
+// episodeHTMLDescription keeps the raw HTML for content:encoded.
+func episodeHTMLDescription(episode Episode) string {
+	return episode.htmlDescription()
+}
+
+// episodeSubtitle is a short excerpt: the first sentence of the description,
+// truncated to maxLen characters.
+func episodeSubtitle(episode Episode, maxLen int) string {
+	return truncateText(firstSentence(normalizeWhitespace(stripHTML(episode.Description))), maxLen)
+}
+
+// channelPubDate uses the series publication date, falling back to the
+// newest episode when it is missing or unparseable.
+func channelPubDate(seriesData *SeriesData, now time.Time, loc *time.Location) string {
+	if t, err := parsePublicationDate(seriesData.PublicationDate); err == nil {
+		return formatPubDate(t, loc)
+	}
+	if t, err := latestEpisodeTime(seriesData.Episodes, now); err == nil {
+		return formatPubDate(t, loc)
+	}
+	return ""
+}
+
+// formatPubDate formats t for RSS, first converting it into loc if set.
+func formatPubDate(t time.Time, loc *time.Location) string {
+	if loc != nil {
+		t = t.In(loc)
+	}
+	return t.Format(time.RFC1123Z)
+}
+
+// defaultEpisodeTypes maps API episode kinds to itunes:episodeType values.
+var defaultEpisodeTypes = map[string]string{
+	"trailer": "trailer",
+	"teaser":  "trailer",
+	"bonus":   "bonus",
+	"extra":   "bonus",
+}
+
+// episodeType resolves the kind through the configured overrides, then the
+// built-in mapping. Anything unknown or invalid is a full episode.
+func episodeType(kind string, overrides map[string]string) string {
+	kind = strings.ToLower(kind)
+	episodeType, ok := overrides[kind]
+	if !ok {
+		episodeType = defaultEpisodeTypes[kind]
+	}
+
+	switch episodeType {
+	case "full", "trailer", "bonus":
+		return episodeType
+	}
+	return "full"
+}
+
+func channelCategories(seriesData *SeriesData, settings Settings) []Category {
+	return mapCategories(seriesData.Categories, settings.CategoryMap, settings.PassUnmappedCategories)
+}
+
+// channelOwner names the publisher as the owner, falling back to the author.
+func channelOwner(seriesData *SeriesData) *Owner {
+	if seriesData.Publisher != "" {
+		return &Owner{Name: seriesData.Publisher}
+	}
+	if seriesData.Author != "" {
+		return &Owner{Name: seriesData.Author}
+	}
+	return nil
+}
+
+// Apple recommends at most 12 keywords in at most 255 characters.
+const (
+	maxKeywords      = 12
+	maxKeywordsChars = 255
+)
+
+// joinKeywords comma-joins the distinct non-empty tags, stopping before
+// either keyword limit would be exceeded.
+func joinKeywords(tags []string) string {
+	seen := make(map[string]bool, len(tags))
+	var keywords []string
+	length := 0
+	for _, tag := range tags {
+		tag = strings.TrimSpace(strings.ReplaceAll(tag, ",", " "))
+		if tag == "" || seen[strings.ToLower(tag)] {
+			continue
+		}
+		if len(keywords) == maxKeywords {
+			break
+		}
+
+		added := utf8.RuneCountInString(tag)
+		if len(keywords) > 0 {
+			added++ // separating comma
+		}
+		if length+added > maxKeywordsChars {
+			break
+		}
+
+		seen[strings.ToLower(tag)] = true
+		keywords = append(keywords, tag)
+		length += added
+	}
+	return strings.Join(keywords, ",")
+}
+
+func episodeAuthor(episode Episode, seriesData *SeriesData) string {
+	if episode.Author != "" {
+		return episode.Author
+	}
+	return seriesData.Author
+}
+
+// episodeDuration returns the length in seconds, or 0 if unknown. When the
+// API has no duration, the slices' latest end time bounds the audio, since
+// slices are positions within the same file rather than separate parts.
+func episodeDuration(episode Episode) int {
+	if episode.AudioDuration > 0 {
+		return int(episode.AudioDuration)
+	}
+	end := 0
+	for _, slice := range episode.AudioSlices {
+		end = max(end, int(slice.End))
+	}
+	if end > 0 {
+		return end
+	}
+	return int(episode.AudioSample.AudioDuration)
+}
+
 func formatDuration(seconds int) string {
 	hours := seconds / 3600
 	minutes := (seconds % 3600) / 60
@@ -56,8 +392,28 @@ func formatDuration(seconds int) string {
 	return fmt.Sprintf("%d:%02d", minutes, secs)
 }
 
-func formatDescriptionWithAvailability(episode Episode, loc *time.Location) string {
-	description := episode.Description
+// affixTitle adds prefix and suffix to title, separated by single spaces.
+// An affix the title already carries is not added again.
+func affixTitle(title, prefix, suffix string) string {
+	title = strings.TrimSpace(title)
+	if prefix = strings.TrimSpace(prefix); prefix != "" && !strings.HasPrefix(title, prefix) {
+		title = prefix + " " + title
+	}
+	if suffix = strings.TrimSpace(suffix); suffix != "" && !strings.HasSuffix(title, suffix) {
+		title = title + " " + suffix
+	}
+	return title
+}
+
+func episodeGUID(episode Episode, source string) string {
+	if source != guidSourceGUID && episode.RSSGUID != "" {
+		return episode.RSSGUID
+	}
+	return episode.GUID
+}
+
+func formatDescriptionWithAvailability(episode Episode, loc *time.Location, maxLen int) string {
+	description := truncateText(stripHTML(episode.Description), maxLen)
 
 	// Find the earliest start date among non-paid availability periods
 	var earliest *time.Time
@@ -68,7 +424,7 @@ func formatDescriptionWithAvailability(episode Episode, loc *time.Location) stri
 		if ap.StartDate == "" {
 			continue
 		}
-		t, err := time.Parse(time.RFC3339, ap.StartDate)
+		t, err := parsePublicationDate(ap.StartDate)
 		if err != nil {
 			continue
 		}
@@ -86,7 +442,143 @@ func formatDescriptionWithAvailability(episode Episode, loc *time.Location) stri
 	return description
 }
 
-func generateRSSFeed(seriesData *SeriesData, allowFutureEpisodes bool, settings Settings) (string, error) {
+// Episode orderings accepted by FeedOptions.SortOrder.
+const (
+	sortNewestFirst = "newest"
+	sortOldestFirst = "oldest"
+)
+
+// FeedOptions controls how GenerateFeed renders a series. The zero value
+// produces a feed with the API's episode order and the current time.
+type FeedOptions struct {
+	Series              Series
+	Settings            Settings
+	AllowFutureEpisodes bool
+	// MaxEpisodes keeps only the first n episodes after sorting; 0 keeps all.
+	MaxEpisodes int
+	Language    string
+	// Explicit is the channel's itunes:explicit value.
+	Explicit bool
+	// Owner and Category replace the values derived from the series data.
+	Owner    *Owner
+	Category string
+	// SortOrder is "newest", "oldest" or empty to keep the API order.
+	SortOrder string
+	// Now is the clock used for date filtering; nil means time.Now.
+	Now func() time.Time
+	// OnWarning, if set, receives non-fatal problems such as episodes with
+	// unparseable dates.
+	OnWarning func(string)
+	// OnItem, if set, receives every episode that ends up in the feed.
+	OnItem func(Episode)
+}
+
+func (o FeedOptions) now() time.Time {
+	if o.Now == nil {
+		return time.Now()
+	}
+	return o.Now()
+}
+
+func (o FeedOptions) warn(msg string) {
+	if o.OnWarning != nil {
+		o.OnWarning(msg)
+	}
+}
+
+// GenerateFeed renders seriesData as an RSS document according to opts.
+func GenerateFeed(seriesData *SeriesData, opts FeedOptions) (string, error) {
+	return marshalRSSFeed(buildRSSFeed(seriesData, opts))
+}
+
+// feedOptions returns the options matching a configured series.
+func feedOptions(series Series, settings Settings, allowFutureEpisodes bool, now time.Time) FeedOptions {
+	return FeedOptions{
+		Series:              series,
+		Settings:            settings,
+		AllowFutureEpisodes: allowFutureEpisodes,
+		Explicit:            series.Explicit,
+		Now:                 func() time.Time { return now },
+	}
+}
+
+// generateRSSFeed renders the series as an RSS document. Non-fatal problems,
+// such as episodes with unparseable dates, are returned as warnings. The
+// output depends on the current time only through now.
+func generateRSSFeed(seriesData *SeriesData, series Series, allowFutureEpisodes bool, settings Settings, now time.Time) (string, []string, error) {
+	var warnings []string
+	opts := feedOptions(series, settings, allowFutureEpisodes, now)
+	opts.OnWarning = func(msg string) { warnings = append(warnings, msg) }
+
+	rssXML, err := GenerateFeed(seriesData, opts)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return rssXML, warnings, nil
+}
+
+// sortEpisodes returns the episodes in the requested order. Episodes with
+// unparseable dates keep their relative order and go last.
+func sortEpisodes(episodes []Episode, order string) []Episode {
+	if order != sortNewestFirst && order != sortOldestFirst {
+		return episodes
+	}
+
+	sorted := slices.Clone(episodes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, errA := parsePublicationDate(sorted[i].PublicationDate)
+		b, errB := parsePublicationDate(sorted[j].PublicationDate)
+		if errA != nil || errB != nil {
+			return errA == nil && errB != nil
+		}
+		if order == sortOldestFirst {
+			return a.Before(b)
+		}
+		return a.After(b)
+	})
+	return sorted
+}
+
+// dedupeEpisodes drops episodes whose feed GUID, as chosen by guidSource,
+// already appears, keeping the one with the newest publication date in its
+// original position. A parseable date beats an unparseable one; on a tie
+// the earlier episode wins. It returns the kept episodes and how many were
+// dropped.
+func dedupeEpisodes(episodes []Episode, guidSource string) ([]Episode, int) {
+	newer := func(a, b Episode) bool {
+		dateA, errA := parsePublicationDate(a.PublicationDate)
+		dateB, errB := parsePublicationDate(b.PublicationDate)
+		if errA != nil || errB != nil {
+			return errA == nil && errB != nil
+		}
+		return dateA.After(dateB)
+	}
+
+	best := make(map[string]int, len(episodes))
+	for i, episode := range episodes {
+		guid := episodeGUID(episode, guidSource)
+		if j, ok := best[guid]; !ok || newer(episode, episodes[j]) {
+			best[guid] = i
+		}
+	}
+	if len(best) == len(episodes) {
+		return episodes, 0
+	}
+
+	kept := make([]Episode, 0, len(best))
+	for i, episode := range episodes {
+		if best[episodeGUID(episode, guidSource)] == i {
+			kept = append(kept, episode)
+		}
+	}
+	return kept, len(episodes) - len(kept)
+}
+
+// buildRSSFeed assembles the feed structure without serializing it.
+func buildRSSFeed(seriesData *SeriesData, opts FeedOptions) *RSSFeed {
+	series, settings, now := opts.Series, opts.Settings, opts.now()
+
 	// Load timezone location, default to UTC if not specified or invalid
 	loc := time.UTC
 	if settings.Timezone != "" {
@@ -95,51 +587,193 @@ func generateRSSFeed(seriesData *SeriesData, allowFutureEpisodes bool, settings
 		}
 	}
 
-	now := time.Now()
+	pubDateLoc := settings.pubDateLocation()
+
+	subtitleLength := settings.SubtitleLength
+	if subtitleLength <= 0 {
+		subtitleLength = defaultSubtitleLength
+	}
+
 	oneWeekFromNow := now.Add(7 * 24 * time.Hour)
 
 	feed := RSSFeed{
 		Version: "2.0",
-		Xmlns:   "http://www.itunes.com/dtds/podcast-1.0.dtd",
 		Channel: Channel{
-			Title:        seriesData.Title,
-			Description:  seriesData.Description,
-			ITunesAuthor: seriesData.Author,
-			ITunesImage:  Image{Href: seriesData.CoverURL},
+			Title:            seriesData.Title,
+			Link:             seriesData.Link,
+			Description:      stripHTML(seriesData.Description),
+			PubDate:          channelPubDate(seriesData, now, pubDateLoc),
+			Copyright:        seriesData.Copyright,
+			ITunesAuthor:     seriesData.Author,
+			ITunesSummary:    channelSummary(seriesData, series),
+			Language:         opts.Language,
+			ITunesOwner:      channelOwner(seriesData),
+			ITunesExplicit:   fmt.Sprintf("%t", opts.Explicit),
+			ITunesCategories: channelCategories(seriesData, settings),
+			ITunesImage:      Image{Href: seriesData.CoverURL},
+			ITunesType:       "episodic",
+			ITunesKeywords:   joinKeywords(seriesData.Tags),
 		},
 	}
 
-	for _, episode := range seriesData.Episodes {
-		episodePubDate, err := time.Parse(time.RFC3339, episode.PublicationDate)
+	// The channel always carries the required itunes elements
+	feed.declareBuiltin("itunes")
+
+	// Self-reference the canonical public address of the feed
+	if selfURL, err := generateS3URL(series.S3Path, settings); err == nil {
+		feed.Channel.AtomLink = &AtomLink{Href: selfURL, Rel: "self", Type: "application/rss+xml"}
+		feed.Channel.PodcastGUID = podcastGUID(selfURL)
+		feed.declareBuiltin("atom")
+		feed.declareBuiltin("podcast")
+	}
+
+	if series.ITunesType != "" {
+		feed.Channel.ITunesType = series.ITunesType
+	}
+	feed.Channel.ITunesNewFeedURL = series.NewFeedURL
+	if series.Complete {
+		feed.Channel.ITunesComplete = "Yes"
+	}
+	if series.Locked {
+		feed.Channel.PodcastLocked = &Locked{Owner: series.LockedOwner, Value: "yes"}
+		feed.declareBuiltin("podcast")
+	}
+	feed.Channel.Extra = rawElements(series.ExtraElements)
+	for _, e := range series.ExtraElements {
+		if prefix, uri, ok := e.namespace(); ok {
+			feed.declare(prefix, uri)
+		}
+	}
+	feed.Channel.TTL = series.TTL
+	if len(series.SkipHours) > 0 {
+		feed.Channel.SkipHours = &SkipHours{Hours: series.SkipHours}
+	}
+	if len(series.SkipDays) > 0 {
+		feed.Channel.SkipDays = &SkipDays{Days: series.SkipDays}
+	}
+	if opts.Owner != nil {
+		feed.Channel.ITunesOwner = opts.Owner
+	}
+	if opts.Category != "" {
+		feed.Channel.ITunesCategories = []Category{{Text: opts.Category}}
+	}
+
+	episodes := seriesData.Episodes
+	if settings.dedupeEpisodes() {
+		var duplicates int
+		episodes, duplicates = dedupeEpisodes(episodes, settings.GUIDSource)
+		if duplicates > 0 {
+			slog.Info("collapsed episodes with duplicate GUIDs", "series", seriesData.GUID, "duplicates", duplicates)
+		}
+	}
+
+	titlePrefix, titleSuffix := series.titleAffixes(settings)
+	missingAudio, tooOld := 0, 0
+	cutoff := now.AddDate(0, 0, -settings.MaxAgeDays)
+	for _, episode := range sortEpisodes(episodes, opts.SortOrder) {
+		if opts.MaxEpisodes > 0 && len(feed.Channel.Items) >= opts.MaxEpisodes {
+			break
+		}
+
+		pubDate := ""
+		episodePubDate, err := parsePublicationDate(episode.PublicationDate)
 		if err != nil {
-			episodePubDate = time.Now()
+			if settings.InvalidDates != invalidDatesOmit {
+				opts.warn(fmt.Sprintf("skipped %q: %v", episode.Title, err))
+				continue
+			}
+		} else {
+			// Skip episodes more than a week in the future unless allowed
+			if !opts.AllowFutureEpisodes && episodePubDate.After(oneWeekFromNow) {
+				continue
+			}
+			// An episode published exactly at the cutoff is kept
+			if settings.MaxAgeDays > 0 && episodePubDate.Before(cutoff) {
+				tooOld++
+				continue
+			}
+			pubDate = formatPubDate(episodePubDate, pubDateLoc)
 		}
 
-		// Skip episodes more than a week in the future unless allowed
-		if !allowFutureEpisodes && episodePubDate.After(oneWeekFromNow) {
+		if settings.skipMissingAudio() && !hasFullAudio(episode, settings.AudioPackages) {
+			missingAudio++
 			continue
 		}
+		enclosure := buildEnclosure(episode, settings.AudioPackages)
 
 		item := Item{
-			Title:       episode.Title,
-			Description: formatDescriptionWithAvailability(episode, loc),
-			PubDate:     episodePubDate.Format(time.RFC1123Z),
-			GUID:        GUID{IsPermaLink: "false", Value: episode.GUID},
-			Enclosure: Enclosure{
-				URL:    episode.AudioURL,
-				Length: fmt.Sprintf("%d", episode.AudioLength),
-				Type:   "audio/mpeg",
-			},
-			ITunesDuration: formatDuration(episode.AudioDuration),
+			Title:             affixTitle(episode.Title, titlePrefix, titleSuffix),
+			Link:              episode.OriginalArticleURL,
+			Description:       formatDescriptionWithAvailability(episode, loc, settings.MaxDescriptionLength),
+			PubDate:           pubDate,
+			GUID:              GUID{IsPermaLink: "false", Value: series.GUIDPrefix + episodeGUID(episode, settings.GUIDSource)},
+			Enclosure:         enclosure,
+			ITunesImage:       episodeImage(episode),
+			ITunesSubtitle:    episodeSubtitle(episode, subtitleLength),
+			ITunesSummary:     episodeSummary(episode),
+			ContentEncoded:    episodeHTMLDescription(episode),
+			ITunesAuthor:      episodeAuthor(episode, seriesData),
+			ITunesEpisodeType: episodeType(episode.Kind, settings.EpisodeTypes),
+			ITunesKeywords:    joinKeywords(episode.Tags),
+		}
+		if duration := episodeDuration(episode); duration > 0 {
+			item.ITunesDuration = formatDuration(duration)
+		}
+		if episode.Blocked {
+			item.ITunesBlock = "yes"
+		}
+		if item.ContentEncoded != "" {
+			feed.declareBuiltin("content")
+		}
+		item.ITunesSeason, item.ITunesEpisode = series.episodeNumbering(episode)
+		if settings.Chapters && len(episode.AudioSlices) > 0 {
+			if url, err := generateS3URL(chaptersS3Path(series.S3Path, episode.GUID), settings); err == nil {
+				item.PodcastChapters = &Chapters{URL: url, Type: chaptersContentType}
+				feed.declareBuiltin("podcast")
+			}
 		}
 
 		feed.Channel.Items = append(feed.Channel.Items, item)
+		if opts.OnItem != nil {
+			opts.OnItem(episode)
+		}
 	}
 
+	if tooOld > 0 {
+		opts.warn(fmt.Sprintf("omitted %d episodes older than %d days", tooOld, settings.MaxAgeDays))
+	}
+	if missingAudio > 0 {
+		opts.warn(fmt.Sprintf("skipped %d episodes without audio", missingAudio))
+	}
+
+	return &feed
+}
+
+func marshalRSSFeed(feed *RSSFeed) (string, error) {
 	xmlData, err := xml.MarshalIndent(feed, "", "  ")
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal XML: %w", err)
 	}
 
-	return xml.Header + string(xmlData), nil
+	rssXML := xml.Header + string(xmlData)
+	if err := validateXML(rssXML); err != nil {
+		return "", fmt.Errorf("generated feed is not well-formed: %w", err)
+	}
+
+	return rssXML, nil
+}
+
+// validateXML re-parses the document to make sure nothing malformed gets
+// written or uploaded.
+func validateXML(document string) error {
+	decoder := xml.NewDecoder(strings.NewReader(document))
+	for {
+		_, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
 }
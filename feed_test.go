@@ -0,0 +1,810 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+var fixedNow = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// loadSeriesFixture reads testdata/series.json.
+func loadSeriesFixture(t *testing.T) *SeriesData {
+	t.Helper()
+	raw, err := os.ReadFile(filepath.Join("testdata", "series.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seriesData SeriesData
+	if err := json.Unmarshal(raw, &seriesData); err != nil {
+		t.Fatalf("failed to parse fixture: %v", err)
+	}
+	return &seriesData
+}
+
+// checkGolden compares got with testdata/name, or rewrites the file when
+// the tests run with -update.
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from %s (run go test -update to accept it):\n%s", path, got)
+	}
+}
+
+func TestGenerateRSSFeedGolden(t *testing.T) {
+	series := Series{GUID: "series-1", S3Path: "s3://bucket/podcasts/briefing.rss"}
+	settings := Settings{PublicBaseURL: "https://feeds.example.com"}
+
+	rssXML, warnings, err := generateRSSFeed(loadSeriesFixture(t), series, false, settings, fixedNow)
+	if err != nil {
+		t.Fatalf("generateRSSFeed() error = %v", err)
+	}
+	checkGolden(t, "series.rss", rssXML)
+
+	if len(warnings) != 2 {
+		t.Errorf("warnings = %q, want one for the bad date and one for the missing audio", warnings)
+	}
+}
+
+func TestDedupeEpisodesByFeedGUID(t *testing.T) {
+	episodes := []Episode{
+		{GUID: "a", RSSGUID: "shared", PublicationDate: "2024-01-01T10:00:00Z"},
+		{GUID: "b", RSSGUID: "shared", PublicationDate: "2024-01-02T10:00:00Z"},
+		{GUID: "c", PublicationDate: "2024-01-03T10:00:00Z"},
+	}
+
+	kept, dropped := dedupeEpisodes(episodes, "")
+	if dropped != 1 || len(kept) != 2 {
+		t.Fatalf("dedupeEpisodes(rss_guid) kept %d, dropped %d; want 2 and 1", len(kept), dropped)
+	}
+	if kept[0].GUID != "b" {
+		t.Errorf("kept %q for the shared rss_guid, want the newer episode %q", kept[0].GUID, "b")
+	}
+
+	if _, dropped := dedupeEpisodes(episodes, "guid"); dropped != 0 {
+		t.Errorf("dedupeEpisodes(guid) dropped %d, want 0 since the emitted GUIDs differ", dropped)
+	}
+}
+
+func TestBuildRSSFeedSkipsMissingAudio(t *testing.T) {
+	seriesData := &SeriesData{Title: "Show", Episodes: []Episode{
+		{GUID: "full", PublicationDate: "2024-01-01", AudioURL: "https://cdn.example.com/full.mp3"},
+		{GUID: "empty", PublicationDate: "2024-01-02"},
+		{GUID: "sample", PublicationDate: "2024-01-03", AudioSample: AudioSample{AudioURL: "https://cdn.example.com/sample.mp3"}},
+		{GUID: "package", PublicationDate: "2024-01-04", AudioPkgs: map[string]string{"hq": "https://cdn.example.com/hq.m4a"}},
+	}}
+	var warnings []string
+	opts := FeedOptions{
+		Settings:  Settings{AudioPackages: []string{"hq"}},
+		Now:       func() time.Time { return fixedNow },
+		OnWarning: func(msg string) { warnings = append(warnings, msg) },
+	}
+
+	feed := buildRSSFeed(seriesData, opts)
+	var got []string
+	for _, item := range feed.Channel.Items {
+		got = append(got, item.GUID.Value)
+	}
+	if len(got) != 2 || got[0] != "full" || got[1] != "package" {
+		t.Errorf("items = %v, want [full package]", got)
+	}
+	if len(warnings) != 1 || warnings[0] != "skipped 2 episodes without audio" {
+		t.Errorf("warnings = %q, want the skipped count", warnings)
+	}
+}
+
+func TestBuildRSSFeedMaxAge(t *testing.T) {
+	seriesData := &SeriesData{Title: "Show", Episodes: []Episode{
+		{GUID: "recent", PublicationDate: "2024-05-30T06:00:00Z", AudioURL: "https://cdn.example.com/recent.mp3"},
+		{GUID: "at-cutoff", PublicationDate: "2024-05-02T12:00:00Z", AudioURL: "https://cdn.example.com/at.mp3"},
+		{GUID: "just-before", PublicationDate: "2024-05-02T11:59:59Z", AudioURL: "https://cdn.example.com/before.mp3"},
+		{GUID: "ancient", PublicationDate: "2020-01-01", AudioURL: "https://cdn.example.com/ancient.mp3"},
+		{GUID: "undated", PublicationDate: "someday", AudioURL: "https://cdn.example.com/undated.mp3"},
+	}}
+	tests := []struct {
+		name         string
+		invalidDates string
+		want         []string
+	}{
+		{"invalid dates skipped", "", []string{"recent", "at-cutoff"}},
+		{"invalid dates kept undated", invalidDatesOmit, []string{"recent", "at-cutoff", "undated"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var warnings []string
+			feed := buildRSSFeed(seriesData, FeedOptions{
+				Settings:  Settings{MaxAgeDays: 30, InvalidDates: tt.invalidDates},
+				Now:       func() time.Time { return fixedNow },
+				OnWarning: func(msg string) { warnings = append(warnings, msg) },
+			})
+			var got []string
+			for _, item := range feed.Channel.Items {
+				got = append(got, item.GUID.Value)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("items = %v, want %v", got, tt.want)
+			}
+			if !slices.Contains(warnings, "omitted 2 episodes older than 30 days") {
+				t.Errorf("warnings = %q, want the omitted count", warnings)
+			}
+		})
+	}
+
+	feed := buildRSSFeed(seriesData, FeedOptions{Now: func() time.Time { return fixedNow }})
+	if len(feed.Channel.Items) != 4 {
+		t.Errorf("without max_age_days got %d items, want every dated episode", len(feed.Channel.Items))
+	}
+}
+
+func TestBuildEnclosureFallback(t *testing.T) {
+	sample := AudioSample{AudioURL: "https://cdn.example.com/sample.mp3", AudioLength: 500}
+	tests := []struct {
+		name    string
+		episode Episode
+		want    Enclosure
+	}{
+		{
+			"primary fields",
+			Episode{AudioURL: "https://cdn.example.com/full.mp3", AudioLength: 1000, AudioSample: sample},
+			Enclosure{URL: "https://cdn.example.com/full.mp3", Length: "1000", Type: "audio/mpeg"},
+		},
+		{
+			"sample length when primary is zero",
+			Episode{AudioURL: "https://cdn.example.com/full.mp3", AudioSample: sample},
+			Enclosure{URL: "https://cdn.example.com/full.mp3", Length: "500", Type: "audio/mpeg"},
+		},
+		{
+			"sample URL when primary is empty",
+			Episode{AudioSample: sample},
+			Enclosure{URL: "https://cdn.example.com/sample.mp3", Length: "500", Type: "audio/mpeg"},
+		},
+		{
+			"no length at all",
+			Episode{AudioURL: "https://cdn.example.com/full.mp3"},
+			Enclosure{URL: "https://cdn.example.com/full.mp3", Type: "audio/mpeg"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildEnclosure(tt.episode, nil); got != tt.want {
+				t.Errorf("buildEnclosure() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildEnclosureAudioPackages(t *testing.T) {
+	episode := Episode{
+		AudioURL:    "https://cdn.example.com/full.mp3",
+		AudioLength: 1000,
+		AudioPkgs: map[string]string{
+			"high":   "https://cdn.example.com/high.m4a",
+			"medium": "https://cdn.example.com/medium.mp3",
+			"empty":  "",
+		},
+	}
+	tests := []struct {
+		name       string
+		preference []string
+		want       Enclosure
+	}{
+		{"first preference", []string{"high", "medium"}, Enclosure{URL: "https://cdn.example.com/high.m4a", Type: "audio/mp4"}},
+		{"order decides", []string{"medium", "high"}, Enclosure{URL: "https://cdn.example.com/medium.mp3", Type: "audio/mpeg"}},
+		{"missing keys skipped", []string{"low", "empty", "medium"}, Enclosure{URL: "https://cdn.example.com/medium.mp3", Type: "audio/mpeg"}},
+		{"none present", []string{"low"}, Enclosure{URL: "https://cdn.example.com/full.mp3", Length: "1000", Type: "audio/mpeg"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildEnclosure(episode, tt.preference); got != tt.want {
+				t.Errorf("buildEnclosure(%q) = %+v, want %+v", tt.preference, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAudioMIMEType(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://cdn.example.com/ep.mp3", "audio/mpeg"},
+		{"https://cdn.example.com/ep.m4a", "audio/mp4"},
+		{"https://cdn.example.com/ep.MP4", "audio/mp4"},
+		{"https://cdn.example.com/ep.aac", "audio/aac"},
+		{"https://cdn.example.com/ep.ogg", "audio/ogg"},
+		{"https://cdn.example.com/ep.opus", "audio/opus"},
+		{"https://cdn.example.com/ep.m4a?token=abc.mp3", "audio/mp4"},
+		{"https://cdn.example.com/ep.ogg#t=10", "audio/ogg"},
+		{"https://cdn.example.com/ep", "audio/mpeg"},
+		{"https://cdn.example.com/ep.xyz", "audio/mpeg"},
+		{"", "audio/mpeg"},
+	}
+	for _, tt := range tests {
+		if got := audioMIMEType(tt.url); got != tt.want {
+			t.Errorf("audioMIMEType(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestEpisodeImage(t *testing.T) {
+	square := "https://images.example.com/square.jpg"
+	empty := ""
+	tests := []struct {
+		name    string
+		episode Episode
+		want    string
+	}{
+		{"square preferred", Episode{CoverURL: "https://images.example.com/cover.jpg", SquareCoverURL: &square}, square},
+		{"cover fallback", Episode{CoverURL: "https://images.example.com/cover.jpg"}, "https://images.example.com/cover.jpg"},
+		{"empty square falls back", Episode{CoverURL: "https://images.example.com/cover.jpg", SquareCoverURL: &empty}, "https://images.example.com/cover.jpg"},
+		{"none", Episode{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := episodeImage(tt.episode)
+			if tt.want == "" {
+				if got != nil {
+					t.Errorf("episodeImage() = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil || got.Href != tt.want {
+				t.Errorf("episodeImage() = %+v, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEpisodeSubtitleAndSummary(t *testing.T) {
+	tests := []struct {
+		name         string
+		description  string
+		maxLen       int
+		wantSubtitle string
+		wantSummary  string
+	}{
+		{
+			"first sentence",
+			"<p>Parliament debates the budget. Then the weather.</p>",
+			255,
+			"Parliament debates the budget.",
+			"Parliament debates the budget. Then the weather.",
+		},
+		{
+			"truncated at a word",
+			"A long opening sentence about many things",
+			20,
+			"A long opening…",
+			"A long opening sentence about many things",
+		},
+		{
+			"version numbers are not sentence ends",
+			"Release 1.2 is out. More soon.",
+			255,
+			"Release 1.2 is out.",
+			"Release 1.2 is out. More soon.",
+		},
+		{"empty", "", 255, "", ""},
+		{"only markup", "<p> </p>", 255, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			episode := Episode{Description: tt.description}
+			if got := episodeSubtitle(episode, tt.maxLen); got != tt.wantSubtitle {
+				t.Errorf("episodeSubtitle() = %q, want %q", got, tt.wantSubtitle)
+			}
+			if got := episodeSummary(episode); got != tt.wantSummary {
+				t.Errorf("episodeSummary() = %q, want %q", got, tt.wantSummary)
+			}
+		})
+	}
+}
+
+func TestChannelSummary(t *testing.T) {
+	html := "<p>From <b>HTML</b></p>"
+	tests := []struct {
+		name       string
+		seriesData SeriesData
+		series     Series
+		want       string
+	}{
+		{"summary override wins", SeriesData{HTMLDescription: &html, Description: "Plain"}, Series{Summary: "Configured"}, "Configured"},
+		{"HTML description stripped", SeriesData{HTMLDescription: &html, Description: "Plain"}, Series{}, "From HTML"},
+		{"description override skips the HTML", SeriesData{HTMLDescription: &html, Description: "Plain"}, Series{DescriptionOverride: "Other"}, "Plain"},
+		{"plain description", SeriesData{Description: "<i>Plain</i>"}, Series{}, "Plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := channelSummary(&tt.seriesData, tt.series); got != tt.want {
+				t.Errorf("channelSummary() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	atLimit := strings.Repeat("a", maxSummaryLength)
+	if got := channelSummary(&SeriesData{Description: atLimit}, Series{}); got != atLimit {
+		t.Errorf("summary of exactly %d characters was changed", maxSummaryLength)
+	}
+	overLimit := strings.Repeat("word ", maxSummaryLength/5) + "end"
+	got := channelSummary(&SeriesData{Description: overLimit}, Series{})
+	if n := utf8.RuneCountInString(got); n > maxSummaryLength || !strings.HasSuffix(got, "…") {
+		t.Errorf("summary over the limit = %d characters ending %q, want at most %d ending with an ellipsis", n, got[len(got)-8:], maxSummaryLength)
+	}
+}
+
+func TestMarshalRSSFeedRejectsMalformedXML(t *testing.T) {
+	feed := &RSSFeed{Version: "2.0", Channel: Channel{Title: "Show"}}
+	if _, err := marshalRSSFeed(feed); err != nil {
+		t.Fatalf("marshalRSSFeed() of a valid feed error = %v", err)
+	}
+
+	// encoding/xml does not check element names, so a bad one produces
+	// a document that does not parse
+	feed.Channel.Extra = []rawElement{{XMLName: xml.Name{Local: "broken><"}, Text: "x"}}
+	_, err := marshalRSSFeed(feed)
+	if err == nil || !strings.Contains(err.Error(), "not well-formed") {
+		t.Errorf("marshalRSSFeed() of a malformed feed error = %v, want the well-formedness guard to trip", err)
+	}
+}
+
+func TestEpisodeAuthorAndBlock(t *testing.T) {
+	tests := []struct {
+		name         string
+		seriesAuthor string
+		episode      Episode
+		wantAuthor   string
+	}{
+		{"episode author", "Newsroom", Episode{Author: "Reporter"}, "Reporter"},
+		{"series author fallback", "Newsroom", Episode{}, "Newsroom"},
+		{"neither", "", Episode{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := episodeAuthor(tt.episode, &SeriesData{Author: tt.seriesAuthor}); got != tt.wantAuthor {
+				t.Errorf("episodeAuthor() = %q, want %q", got, tt.wantAuthor)
+			}
+		})
+	}
+
+	seriesData := &SeriesData{Episodes: []Episode{
+		{GUID: "open", PublicationDate: "2024-01-01", AudioURL: "https://cdn.example.com/open.mp3"},
+		{GUID: "hidden", PublicationDate: "2024-01-02", AudioURL: "https://cdn.example.com/hidden.mp3"},
+	}}
+	applySeriesConfig(seriesData, Series{BlockedEpisodes: []string{"hidden"}})
+	feed := buildRSSFeed(seriesData, FeedOptions{Now: func() time.Time { return fixedNow }})
+	for i, wantBlock := range []bool{false, true} {
+		itemXML, err := xml.Marshal(feed.Channel.Items[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(itemXML), "itunes:author") {
+			t.Errorf("item %d has an empty itunes:author element", i)
+		}
+		if got := strings.Contains(string(itemXML), "<itunes:block>yes</itunes:block>"); got != wantBlock {
+			t.Errorf("item %d has itunes:block = %v, want %v", i, got, wantBlock)
+		}
+	}
+}
+
+func TestChannelPubDate(t *testing.T) {
+	episodes := []Episode{
+		{PublicationDate: "2024-05-20T06:00:00Z"},
+		{PublicationDate: "2024-05-28T06:00:00Z"},
+		{PublicationDate: "2024-09-01T06:00:00Z"}, // too far in the future
+		{PublicationDate: "soon"},
+	}
+	tests := []struct {
+		name       string
+		seriesData SeriesData
+		want       string
+	}{
+		{"series date", SeriesData{PublicationDate: "2024-05-30T06:00:00Z", Episodes: episodes}, "Thu, 30 May 2024 06:00:00 +0000"},
+		{"series date missing", SeriesData{Episodes: episodes}, "Tue, 28 May 2024 06:00:00 +0000"},
+		{"series date unparseable", SeriesData{PublicationDate: "n/a", Episodes: episodes}, "Tue, 28 May 2024 06:00:00 +0000"},
+		{"no usable dates", SeriesData{Episodes: []Episode{{PublicationDate: "soon"}}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := channelPubDate(&tt.seriesData, fixedNow, nil); got != tt.want {
+				t.Errorf("channelPubDate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEpisodeType(t *testing.T) {
+	tests := []struct {
+		kind      string
+		overrides map[string]string
+		want      string
+	}{
+		{"trailer", nil, "trailer"},
+		{"teaser", nil, "trailer"},
+		{"bonus", nil, "bonus"},
+		{"Extra", nil, "bonus"},
+		{"full", nil, "full"},
+		{"", nil, "full"},
+		{"interview", nil, "full"},
+		{"interview", map[string]string{"interview": "bonus"}, "bonus"},
+		{"teaser", map[string]string{"teaser": "full"}, "full"},
+		{"teaser", map[string]string{"teaser": "nonsense"}, "full"},
+	}
+	for _, tt := range tests {
+		if got := episodeType(tt.kind, tt.overrides); got != tt.want {
+			t.Errorf("episodeType(%q, %v) = %q, want %q", tt.kind, tt.overrides, got, tt.want)
+		}
+	}
+}
+
+func TestJoinKeywords(t *testing.T) {
+	many := make([]string, 20)
+	for i := range many {
+		many[i] = fmt.Sprintf("tag%d", i)
+	}
+	long := []string{strings.Repeat("a", 200), strings.Repeat("b", 60), "short"}
+
+	tests := []struct {
+		name string
+		tags []string
+		want string
+	}{
+		{"joined", []string{"news", "politics"}, "news,politics"},
+		{"trimmed and deduplicated", []string{" news ", "", "News", "politics"}, "news,politics"},
+		{"commas in tags", []string{"news, weekly"}, "news  weekly"},
+		{"at most 12", many, strings.Join(many[:12], ",")},
+		{"at most 255 characters", long, strings.Repeat("a", 200)},
+		{"empty", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := joinKeywords(tt.tags); got != tt.want {
+				t.Errorf("joinKeywords() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFeedURL(t *testing.T) {
+	seriesData := &SeriesData{Title: "Show"}
+	for _, newFeedURL := range []string{"", "https://feeds.example.com/moved.rss"} {
+		feed := buildRSSFeed(seriesData, FeedOptions{Series: Series{NewFeedURL: newFeedURL}, Now: func() time.Time { return fixedNow }})
+		rssXML, err := marshalRSSFeed(feed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "<itunes:new-feed-url>" + newFeedURL + "</itunes:new-feed-url>"
+		if got := strings.Contains(rssXML, "itunes:new-feed-url"); got != (newFeedURL != "") {
+			t.Errorf("new-feed-url %q: element present = %v", newFeedURL, got)
+		}
+		if newFeedURL != "" && !strings.Contains(rssXML, want) {
+			t.Errorf("feed does not contain %s", want)
+		}
+	}
+
+	for _, bad := range []string{"feeds.example.com/moved.rss", "/moved.rss", "ftp://example.com/feed.rss"} {
+		config := SeriesConfig{Series: []Series{{GUID: "abc", S3Path: "s3://bucket/show.rss", NewFeedURL: bad}}}
+		if err := config.validate(); err == nil {
+			t.Errorf("validate() with new_feed_url %q succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestAffixTitle(t *testing.T) {
+	tests := []struct {
+		name, title, prefix, suffix, want string
+	}{
+		{"none", "Episode 1", "", "", "Episode 1"},
+		{"prefix only", "Episode 1", "[Archive]", "", "[Archive] Episode 1"},
+		{"suffix only", "Episode 1", "", "(rerun)", "Episode 1 (rerun)"},
+		{"both", "Episode 1", "[Archive]", "(rerun)", "[Archive] Episode 1 (rerun)"},
+		{"whitespace trimmed", "  Episode 1 ", " [Archive] ", " (rerun) ", "[Archive] Episode 1 (rerun)"},
+		{"not applied twice", "[Archive] Episode 1 (rerun)", "[Archive]", "(rerun)", "[Archive] Episode 1 (rerun)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := affixTitle(tt.title, tt.prefix, tt.suffix); got != tt.want {
+				t.Errorf("affixTitle() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	settings := Settings{TitlePrefix: "[Global]", TitleSuffix: "(global)"}
+	if prefix, suffix := (Series{TitlePrefix: "[Series]"}).titleAffixes(settings); prefix != "[Series]" || suffix != "(global)" {
+		t.Errorf("titleAffixes() = %q, %q, want the series prefix and the global suffix", prefix, suffix)
+	}
+}
+
+func TestGUIDPrefix(t *testing.T) {
+	seriesData := &SeriesData{Episodes: []Episode{
+		{GUID: "ep-1", PublicationDate: "2024-01-01", AudioURL: "https://cdn.example.com/1.mp3"},
+		{GUID: "ep-2", RSSGUID: "rss-2", PublicationDate: "2024-01-02", AudioURL: "https://cdn.example.com/2.mp3"},
+	}}
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"", []string{"ep-1", "rss-2"}},
+		{"briefing-", []string{"briefing-ep-1", "briefing-rss-2"}},
+	}
+	for _, tt := range tests {
+		feed := buildRSSFeed(seriesData, FeedOptions{Series: Series{GUIDPrefix: tt.prefix}, Now: func() time.Time { return fixedNow }})
+		for i, item := range feed.Channel.Items {
+			if item.GUID.Value != tt.want[i] {
+				t.Errorf("prefix %q: GUID = %q, want %q", tt.prefix, item.GUID.Value, tt.want[i])
+			}
+			if item.GUID.IsPermaLink != "false" {
+				t.Errorf("prefix %q: isPermaLink = %q, want false", tt.prefix, item.GUID.IsPermaLink)
+			}
+		}
+	}
+}
+
+func TestITunesComplete(t *testing.T) {
+	seriesData := &SeriesData{Title: "Show", Episodes: []Episode{
+		{GUID: "ep-1", PublicationDate: "2024-01-01", AudioURL: "https://cdn.example.com/1.mp3"},
+	}}
+	for _, complete := range []bool{false, true} {
+		feed := buildRSSFeed(seriesData, FeedOptions{Series: Series{Complete: complete}, Now: func() time.Time { return fixedNow }})
+		rssXML, err := marshalRSSFeed(feed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := 0
+		if complete {
+			want = 1
+		}
+		if got := strings.Count(rssXML, "<itunes:complete>Yes</itunes:complete>"); got != want {
+			t.Errorf("complete = %v: %d itunes:complete elements, want %d", complete, got, want)
+		}
+		if i := strings.Index(rssXML, "<item>"); strings.Contains(rssXML[i:], "itunes:complete") {
+			t.Errorf("complete = %v: itunes:complete appears inside an item", complete)
+		}
+	}
+}
+
+func TestEpisodeDuration(t *testing.T) {
+	slices := []AudioSlice{{Start: 0, End: 600}, {Start: 1200, End: 1800}, {Start: 600, End: 1200}}
+	tests := []struct {
+		name    string
+		episode Episode
+		want    int
+	}{
+		{"API duration", Episode{AudioDuration: 900, AudioSlices: slices}, 900},
+		{"latest slice end", Episode{AudioSlices: slices}, 1800},
+		{"overlapping slices", Episode{AudioSlices: []AudioSlice{{Start: 0, End: 300}, {Start: 100, End: 200}}}, 300},
+		{"sample duration", Episode{AudioSample: AudioSample{AudioDuration: 120}}, 120},
+		{"unknown", Episode{}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := episodeDuration(tt.episode); got != tt.want {
+				t.Errorf("episodeDuration() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	for seconds, want := range map[int]string{59: "0:59", 615: "10:15", 3600: "1:00:00", 3725: "1:02:05"} {
+		if got := formatDuration(seconds); got != want {
+			t.Errorf("formatDuration(%d) = %q, want %q", seconds, got, want)
+		}
+	}
+
+	feed := buildRSSFeed(&SeriesData{Episodes: []Episode{
+		{GUID: "ep-1", PublicationDate: "2024-01-01", AudioURL: "https://cdn.example.com/1.mp3"},
+	}}, FeedOptions{Now: func() time.Time { return fixedNow }})
+	if got := feed.Channel.Items[0].ITunesDuration; got != "" {
+		t.Errorf("itunes:duration without any duration = %q, want it omitted", got)
+	}
+}
+
+func TestGenerateRSSFeedNilPointerFields(t *testing.T) {
+	seriesData := &SeriesData{
+		GUID:  "series-1",
+		Title: "Show",
+		Episodes: []Episode{{
+			GUID:            "ep-1",
+			Title:           "Episode",
+			PublicationDate: "2024-05-30T06:00:00Z",
+			AudioURL:        "https://cdn.example.com/ep-1.mp3",
+		}},
+	}
+
+	rssXML, _, err := generateRSSFeed(seriesData, Series{GUID: "series-1"}, false, Settings{}, fixedNow)
+	if err != nil {
+		t.Fatalf("generateRSSFeed() error = %v", err)
+	}
+	if !strings.Contains(rssXML, "<guid") {
+		t.Errorf("feed has no items:\n%s", rssXML)
+	}
+	if got := stringValue(nil); got != "" {
+		t.Errorf("stringValue(nil) = %q, want empty", got)
+	}
+}
+
+func TestScheduleHints(t *testing.T) {
+	seriesData := &SeriesData{GUID: "series-1", Title: "Show"}
+	now := FeedOptions{Now: func() time.Time { return fixedNow }}
+
+	feed := buildRSSFeed(seriesData, now)
+	out, err := xml.Marshal(feed.Channel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tag := range []string{"<ttl>", "<skipHours>", "<skipDays>"} {
+		if strings.Contains(string(out), tag) {
+			t.Errorf("unset hints emitted %s", tag)
+		}
+	}
+
+	opts := now
+	opts.Series = Series{TTL: 90, SkipHours: []int{1, 2}, SkipDays: []string{"Saturday", "Sunday"}}
+	feed = buildRSSFeed(seriesData, opts)
+	out, err = xml.Marshal(feed.Channel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<ttl>90</ttl>",
+		"<skipHours><hour>1</hour><hour>2</hour></skipHours>",
+		"<skipDays><day>Saturday</day><day>Sunday</day></skipDays>",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("channel is missing %s:\n%s", want, out)
+		}
+	}
+}
+
+func TestPodcastLocked(t *testing.T) {
+	seriesData := &SeriesData{GUID: "series-1", Title: "Show"}
+
+	rssXML, _, err := generateRSSFeed(seriesData, Series{Locked: true, LockedOwner: "owner@example.com"}, false, Settings{}, fixedNow)
+	if err != nil {
+		t.Fatalf("generateRSSFeed() error = %v", err)
+	}
+	if !strings.Contains(rssXML, `<podcast:locked owner="owner@example.com">yes</podcast:locked>`) || !strings.Contains(rssXML, `xmlns:podcast=`) {
+		t.Errorf("locked feed lacks the element or its namespace:\n%s", rssXML)
+	}
+
+	rssXML, _, err = generateRSSFeed(seriesData, Series{LockedOwner: "owner@example.com"}, false, Settings{}, fixedNow)
+	if err != nil {
+		t.Fatalf("generateRSSFeed() error = %v", err)
+	}
+	if strings.Contains(rssXML, "podcast:locked") {
+		t.Errorf("unlocked feed has podcast:locked:\n%s", rssXML)
+	}
+}
+
+func TestMaxDescriptionLength(t *testing.T) {
+	description := "<p>Parliament debates the budget for the coming year.</p>"
+	seriesData := &SeriesData{Title: "Show", Episodes: []Episode{{
+		GUID:            "ep-1",
+		Description:     description,
+		HTMLDescription: &description,
+		PublicationDate: "2024-05-30T06:00:00Z",
+		AudioURL:        "https://cdn.example.com/ep-1.mp3",
+	}}}
+
+	feed := buildRSSFeed(seriesData, FeedOptions{Settings: Settings{MaxDescriptionLength: 30}, Now: func() time.Time { return fixedNow }})
+	item := feed.Channel.Items[0]
+	if item.Description != "Parliament debates the…" {
+		t.Errorf("description = %q, want it cut at a word", item.Description)
+	}
+	if item.ContentEncoded != description {
+		t.Errorf("content:encoded = %q, want the full HTML", item.ContentEncoded)
+	}
+
+	feed = buildRSSFeed(seriesData, FeedOptions{Now: func() time.Time { return fixedNow }})
+	if got := feed.Channel.Items[0].Description; got != "Parliament debates the budget for the coming year." {
+		t.Errorf("description without a limit = %q", got)
+	}
+}
+
+func TestFormatPubDateTimezone(t *testing.T) {
+	loc := Settings{PubDateTimezone: "Europe/Helsinki"}.pubDateLocation()
+	if loc == nil {
+		t.Fatal("pubDateLocation() = nil for Europe/Helsinki")
+	}
+	tests := []struct {
+		in, want string
+	}{
+		{"2024-01-15T10:00:00Z", "Mon, 15 Jan 2024 12:00:00 +0200"},
+		{"2024-06-15T10:00:00Z", "Sat, 15 Jun 2024 13:00:00 +0300"},
+		// Clocks go forward at 01:00 UTC on the last Sunday of March
+		{"2024-03-31T00:59:59Z", "Sun, 31 Mar 2024 02:59:59 +0200"},
+		{"2024-03-31T01:00:00Z", "Sun, 31 Mar 2024 04:00:00 +0300"},
+		// and back at 01:00 UTC on the last Sunday of October
+		{"2024-10-27T00:30:00Z", "Sun, 27 Oct 2024 03:30:00 +0300"},
+		{"2024-10-27T01:30:00Z", "Sun, 27 Oct 2024 03:30:00 +0200"},
+		{"2024-06-15T16:00:00+03:00", "Sat, 15 Jun 2024 16:00:00 +0300"},
+	}
+	for _, tt := range tests {
+		pubDate, err := parsePublicationDate(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := formatPubDate(pubDate, loc); got != tt.want {
+			t.Errorf("formatPubDate(%s) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	pubDate, _ := parsePublicationDate("2024-06-15T10:00:00Z")
+	if got := formatPubDate(pubDate, nil); got != "Sat, 15 Jun 2024 10:00:00 +0000" {
+		t.Errorf("formatPubDate() without a zone = %q, want the API's offset", got)
+	}
+}
+
+func TestNamespacesOnlyWhenUsed(t *testing.T) {
+	html := "<p>Rich</p>"
+	minimal := &SeriesData{GUID: "series-1", Title: "Show", Episodes: []Episode{{
+		GUID: "ep-1", PublicationDate: "2024-05-30T06:00:00Z", AudioURL: "https://cdn.example.com/ep-1.mp3",
+	}}}
+	rich := &SeriesData{GUID: "series-1", Title: "Show", Episodes: []Episode{{
+		GUID: "ep-1", PublicationDate: "2024-05-30T06:00:00Z", AudioURL: "https://cdn.example.com/ep-1.mp3", HTMLDescription: &html,
+	}}}
+	tests := []struct {
+		name       string
+		seriesData *SeriesData
+		series     Series
+		want       []string
+	}{
+		{"minimal", minimal, Series{}, []string{"itunes"}},
+		{"self link", minimal, Series{S3Path: "s3://bucket/show.rss"}, []string{"atom", "itunes", "podcast"}},
+		{"HTML content", rich, Series{}, []string{"content", "itunes"}},
+	}
+	declared := regexp.MustCompile(`xmlns:(\w+)=`)
+	used := regexp.MustCompile(`<(\w+):`)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rssXML, _, err := generateRSSFeed(tt.seriesData, tt.series, false, Settings{}, fixedNow)
+			if err != nil {
+				t.Fatalf("generateRSSFeed() error = %v", err)
+			}
+			var gotDeclared, gotUsed []string
+			for _, m := range declared.FindAllStringSubmatch(rssXML, -1) {
+				gotDeclared = append(gotDeclared, m[1])
+			}
+			for _, m := range used.FindAllStringSubmatch(rssXML, -1) {
+				if !slices.Contains(gotUsed, m[1]) {
+					gotUsed = append(gotUsed, m[1])
+				}
+			}
+			slices.Sort(gotDeclared)
+			slices.Sort(gotUsed)
+			if !slices.Equal(gotDeclared, tt.want) {
+				t.Errorf("declared namespaces = %q, want %q", gotDeclared, tt.want)
+			}
+			if !slices.Equal(gotUsed, gotDeclared) {
+				t.Errorf("used prefixes %q differ from declared %q", gotUsed, gotDeclared)
+			}
+		})
+	}
+
+	var feed RSSFeed
+	feed.declareBuiltin("itunes")
+	feed.declareBuiltin("itunes")
+	if len(feed.Namespaces) != 1 {
+		t.Errorf("declaring twice gave %d attributes, want 1", len(feed.Namespaces))
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// maxStatusHistory is the number of status messages kept for the history
+// view.
+const maxStatusHistory = 100
+
+type statusEntry struct {
+	at      time.Time
+	message string
+}
+
+// statusHistory is a rolling record of status messages, oldest first.
+type statusHistory []statusEntry
+
+func (h statusHistory) add(message string, at time.Time) statusHistory {
+	h = append(h, statusEntry{at: at, message: message})
+	if over := len(h) - maxStatusHistory; over > 0 {
+		h = h[over:]
+	}
+	return h
+}
+
+// lines renders one row per line of every message, continuation lines
+// indented under the timestamp.
+func (h statusHistory) lines() []string {
+	var lines []string
+	for _, entry := range h {
+		stamp := entry.at.Format("15:04:05") + "  "
+		for i, line := range strings.Split(entry.message, "\n") {
+			if i > 0 {
+				stamp = strings.Repeat(" ", len(stamp))
+			}
+			lines = append(lines, stamp+line)
+		}
+	}
+	return lines
+}
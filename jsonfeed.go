@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// jsonFeedVersion identifies the JSON Feed 1.1 format.
+const jsonFeedVersion = "https://jsonfeed.org/version/1.1"
+
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url,omitempty"`
+	FeedURL     string         `json:"feed_url,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Icon        string         `json:"icon,omitempty"`
+	Authors     []jsonAuthor   `json:"authors,omitempty"`
+	Language    string         `json:"language,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url,omitempty"`
+	Title         string           `json:"title,omitempty"`
+	ContentHTML   string           `json:"content_html,omitempty"`
+	ContentText   string           `json:"content_text,omitempty"`
+	Summary       string           `json:"summary,omitempty"`
+	Image         string           `json:"image,omitempty"`
+	DatePublished string           `json:"date_published,omitempty"`
+	Authors       []jsonAuthor     `json:"authors,omitempty"`
+	Tags          []string         `json:"tags,omitempty"`
+	Attachments   []jsonAttachment `json:"attachments,omitempty"`
+}
+
+type jsonAttachment struct {
+	URL         string `json:"url"`
+	MIMEType    string `json:"mime_type"`
+	SizeInBytes int64  `json:"size_in_bytes,omitempty"`
+}
+
+// GenerateJSONFeed renders seriesData as a JSON Feed 1.1 document. Episodes
+// are selected exactly as for the RSS feed.
+func GenerateJSONFeed(seriesData *SeriesData, opts FeedOptions) (string, error) {
+	return marshalJSONFeed(buildRSSFeed(seriesData, opts))
+}
+
+// marshalJSONFeed serializes the assembled feed as a JSON Feed document.
+func marshalJSONFeed(feed *RSSFeed) (string, error) {
+	data, err := json.MarshalIndent(buildJSONFeed(feed), "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal JSON feed: %w", err)
+	}
+	return string(data) + "\n", nil
+}
+
+// generateJSONFeed mirrors generateRSSFeed for the JSON Feed format.
+func generateJSONFeed(seriesData *SeriesData, series Series, allowFutureEpisodes bool, settings Settings, now time.Time) (string, []string, error) {
+	var warnings []string
+	opts := feedOptions(series, settings, allowFutureEpisodes, now)
+	opts.OnWarning = func(msg string) { warnings = append(warnings, msg) }
+
+	document, err := GenerateJSONFeed(seriesData, opts)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return document, warnings, nil
+}
+
+// buildJSONFeed converts the assembled RSS feed into a JSON Feed.
+func buildJSONFeed(feed *RSSFeed) jsonFeed {
+	ch := feed.Channel
+	jf := jsonFeed{
+		Version:     jsonFeedVersion,
+		Title:       ch.Title,
+		HomePageURL: ch.Link,
+		Description: ch.Description,
+		Icon:        ch.ITunesImage.Href,
+		Language:    ch.Language,
+		Items:       []jsonFeedItem{},
+	}
+	if ch.AtomLink != nil {
+		jf.FeedURL = ch.AtomLink.Href
+	}
+	if ch.ITunesAuthor != "" {
+		jf.Authors = []jsonAuthor{{Name: ch.ITunesAuthor}}
+	}
+
+	for _, item := range ch.Items {
+		ji := jsonFeedItem{
+			ID:          item.GUID.Value,
+			URL:         item.Link,
+			Title:       item.Title,
+			ContentHTML: item.ContentEncoded,
+			ContentText: item.Description,
+			Summary:     item.ITunesSubtitle,
+		}
+		// The spec requires content_html or content_text on every item
+		if ji.ContentHTML == "" && ji.ContentText == "" {
+			ji.ContentText = item.Title
+		}
+		if item.ITunesImage != nil {
+			ji.Image = item.ITunesImage.Href
+		}
+		if t, err := time.Parse(time.RFC1123Z, item.PubDate); err == nil {
+			ji.DatePublished = t.Format(time.RFC3339)
+		}
+		if item.ITunesAuthor != "" {
+			ji.Authors = []jsonAuthor{{Name: item.ITunesAuthor}}
+		}
+		if item.ITunesKeywords != "" {
+			ji.Tags = strings.Split(item.ITunesKeywords, ",")
+		}
+		if item.Enclosure.URL != "" {
+			size, _ := strconv.ParseInt(item.Enclosure.Length, 10, 64)
+			ji.Attachments = []jsonAttachment{{URL: item.Enclosure.URL, MIMEType: item.Enclosure.Type, SizeInBytes: size}}
+		}
+		jf.Items = append(jf.Items, ji)
+	}
+	return jf
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGenerateJSONFeedRequiredFields(t *testing.T) {
+	series := Series{GUID: "series-1", S3Path: "s3://bucket/podcasts/briefing.json"}
+	settings := Settings{PublicBaseURL: "https://feeds.example.com", FeedFormat: feedFormatJSON}
+
+	document, _, err := generateJSONFeed(loadSeriesFixture(t), series, false, settings, fixedNow)
+	if err != nil {
+		t.Fatalf("generateJSONFeed() error = %v", err)
+	}
+
+	// Decode generically so the check follows the spec, not our structs
+	var feed map[string]any
+	if err := json.Unmarshal([]byte(document), &feed); err != nil {
+		t.Fatalf("JSON feed does not parse: %v", err)
+	}
+	if feed["version"] != jsonFeedVersion {
+		t.Errorf("version = %v, want %q", feed["version"], jsonFeedVersion)
+	}
+	if title, _ := feed["title"].(string); title == "" {
+		t.Error("title is missing")
+	}
+	items, ok := feed["items"].([]any)
+	if !ok || len(items) == 0 {
+		t.Fatalf("items = %v, want a non-empty array", feed["items"])
+	}
+	for i, raw := range items {
+		item := raw.(map[string]any)
+		if id, _ := item["id"].(string); id == "" {
+			t.Errorf("items[%d] has no id", i)
+		}
+		html, _ := item["content_html"].(string)
+		text, _ := item["content_text"].(string)
+		if html == "" && text == "" {
+			t.Errorf("items[%d] has neither content_html nor content_text", i)
+		}
+		attachments, _ := item["attachments"].([]any)
+		for _, a := range attachments {
+			attachment := a.(map[string]any)
+			if attachment["url"] == "" || attachment["mime_type"] == "" {
+				t.Errorf("items[%d] attachment = %v, want url and mime_type", i, attachment)
+			}
+		}
+	}
+
+	if got := settings.feedContentType(); got != "application/json" {
+		t.Errorf("feedContentType() = %q, want application/json", got)
+	}
+}
+
+func TestBuildJSONFeedItem(t *testing.T) {
+	feed := &RSSFeed{Channel: Channel{
+		Title: "Show",
+		Items: []Item{{
+			Title:          "Episode",
+			GUID:           GUID{Value: "ep-1"},
+			PubDate:        "Thu, 30 May 2024 06:00:00 +0000",
+			ITunesKeywords: "news,finance",
+			Enclosure:      Enclosure{URL: "https://cdn.example.com/ep-1.mp3", Type: "audio/mpeg", Length: "1234"},
+		}},
+	}}
+
+	item := buildJSONFeed(feed).Items[0]
+	if item.ID != "ep-1" || item.ContentText != "Episode" {
+		t.Errorf("id, content_text = %q, %q, want the guid and the title fallback", item.ID, item.ContentText)
+	}
+	if item.DatePublished != "2024-05-30T06:00:00Z" {
+		t.Errorf("date_published = %q, want RFC 3339", item.DatePublished)
+	}
+	if len(item.Tags) != 2 || item.Tags[1] != "finance" {
+		t.Errorf("tags = %q", item.Tags)
+	}
+	want := jsonAttachment{URL: "https://cdn.example.com/ep-1.mp3", MIMEType: "audio/mpeg", SizeInBytes: 1234}
+	if len(item.Attachments) != 1 || item.Attachments[0] != want {
+		t.Errorf("attachments = %+v, want %+v", item.Attachments, want)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// latestEpisode is one row of the latest-episode overview.
+type latestEpisode struct {
+	GUID   string
+	Title  string
+	Latest time.Time
+	Err    error
+}
+
+func (l latestEpisode) daysSince(now time.Time) int {
+	return int(now.Sub(l.Latest).Hours() / 24)
+}
+
+// isStale reports whether the latest episode is older than the threshold.
+func isStale(latest, now time.Time, threshold time.Duration) bool {
+	return now.Sub(latest) > threshold
+}
+
+// fetchLatestEpisodes looks up the newest episode of every series as of now,
+// running at most metadata_concurrency requests at once. Failures are
+// recorded per row rather than aborting the whole run.
+func fetchLatestEpisodes(ctx context.Context, series []Series, settings Settings, now time.Time) []latestEpisode {
+	rows := make([]latestEpisode, len(series))
+	sem := make(chan struct{}, settings.metadataConcurrency())
+	var wg sync.WaitGroup
+
+	for i, s := range series {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			rows[i] = latestEpisode{GUID: s.GUID, Title: s.GUID}
+			seriesData, err := fetchSeriesData(ctx, s.GUID, settings)
+			if err != nil {
+				rows[i].Err = err
+				return
+			}
+			rows[i].Title = seriesData.Title
+			rows[i].Latest, rows[i].Err = latestEpisodeTime(seriesData.Episodes, now)
+		}()
+	}
+	wg.Wait()
+
+	sortByStaleness(rows)
+	return rows
+}
+
+// sortByStaleness puts failed lookups first, then the oldest feeds.
+func sortByStaleness(rows []latestEpisode) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		if (rows[i].Err != nil) != (rows[j].Err != nil) {
+			return rows[i].Err != nil
+		}
+		return rows[i].Latest.Before(rows[j].Latest)
+	})
+}
+
+func formatLatestTable(rows []latestEpisode, now time.Time) string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TITLE\tLATEST EPISODE\tDAYS SINCE")
+	for _, row := range rows {
+		if row.Err != nil {
+			fmt.Fprintf(w, "%s\terror\t-\n", row.Title)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\n", row.Title, row.Latest.Format("Jan 2, 2006"), row.daysSince(now))
+	}
+	w.Flush()
+	return sb.String()
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sortMode orders the series list in the TUI.
+type sortMode int
+
+const (
+	sortConfig sortMode = iota
+	sortName
+	sortRank
+	sortLatest
+)
+
+func (s sortMode) String() string {
+	switch s {
+	case sortName:
+		return "name"
+	case sortRank:
+		return "rank"
+	case sortLatest:
+		return "latest episode"
+	}
+	return "config order"
+}
+
+// next cycles through name, rank and latest episode; config order is only
+// the initial state.
+func (s sortMode) next() sortMode {
+	if s == sortLatest {
+		return sortName
+	}
+	return s + 1
+}
+
+// rank returns the series' position in the given ranking window ("daily",
+// "weekly" or "monthly"), or 0 if it is not ranked.
+func (r Rankings) rank(window string) int {
+	switch window {
+	case "daily":
+		return r.Daily
+	case "monthly":
+		return r.Monthly
+	}
+	return r.Weekly
+}
+
+// seriesRow pairs a series with its prefetched metadata for sorting.
+type seriesRow struct {
+	series Series
+	meta   seriesMetadata
+}
+
+func (r seriesRow) name() string {
+	if r.meta.state == metadataLoaded && r.meta.data.Title != "" {
+		return r.meta.data.Title
+	}
+	return displayPath(r.series.S3Path)
+}
+
+// sortRows orders rows by mode. Series lacking the sort key, such as
+// unranked series or ones whose metadata failed to load, go last.
+func sortRows(rows []seriesRow, mode sortMode, window string, now time.Time) {
+	if mode == sortConfig {
+		return
+	}
+
+	rank := func(r seriesRow) int {
+		if r.meta.state != metadataLoaded {
+			return 0
+		}
+		return r.meta.data.Rankings.rank(window)
+	}
+	latest := func(r seriesRow) (time.Time, bool) {
+		if r.meta.state != metadataLoaded {
+			return time.Time{}, false
+		}
+		t, err := latestEpisodeTime(r.meta.data.Episodes, now)
+		return t, err == nil
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		switch mode {
+		case sortRank:
+			ra, rb := rank(a), rank(b)
+			if (ra > 0) != (rb > 0) {
+				return ra > 0
+			}
+			if ra != rb {
+				return ra < rb
+			}
+		case sortLatest:
+			ta, okA := latest(a)
+			tb, okB := latest(b)
+			if okA != okB {
+				return okA
+			}
+			if !ta.Equal(tb) {
+				return ta.After(tb)
+			}
+		}
+		return strings.ToLower(a.name()) < strings.ToLower(b.name())
+	})
+}
+
+// rankLabel is shown next to ranked series, e.g. "#3".
+func rankLabel(meta seriesMetadata, window string) string {
+	if meta.state != metadataLoaded {
+		return ""
+	}
+	if rank := meta.data.Rankings.rank(window); rank > 0 {
+		return fmt.Sprintf("#%d", rank)
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestSortRows(t *testing.T) {
+	loaded := func(title string, weekly int, published string) seriesMetadata {
+		data := &SeriesData{Title: title, Rankings: Rankings{Weekly: weekly, Daily: 10 - weekly}}
+		if published != "" {
+			data.Episodes = []Episode{{PublicationDate: published}}
+		}
+		return seriesMetadata{state: metadataLoaded, data: data}
+	}
+	rows := []seriesRow{
+		{Series{S3Path: "s3://bucket/b.rss"}, loaded("bravo", 0, "2024-05-20T06:00:00Z")},
+		{Series{S3Path: "s3://bucket/failed.rss"}, seriesMetadata{state: metadataFailed, err: errors.New("boom")}},
+		{Series{S3Path: "s3://bucket/c.rss"}, loaded("Charlie", 2, "2024-05-30T06:00:00Z")},
+		{Series{S3Path: "s3://bucket/a.rss"}, loaded("Alpha", 1, "")},
+		{Series{S3Path: "s3://bucket/d.rss"}, loaded("delta", 5, "2024-05-25T06:00:00Z")},
+	}
+	names := func(rows []seriesRow) []string {
+		var out []string
+		for _, r := range rows {
+			out = append(out, r.name())
+		}
+		return out
+	}
+
+	tests := []struct {
+		mode   sortMode
+		window string
+		want   []string
+	}{
+		{sortConfig, "weekly", []string{"bravo", "bucket/failed.rss", "Charlie", "Alpha", "delta"}},
+		{sortName, "weekly", []string{"Alpha", "bravo", "bucket/failed.rss", "Charlie", "delta"}},
+		{sortRank, "weekly", []string{"Alpha", "Charlie", "delta", "bravo", "bucket/failed.rss"}},
+		{sortRank, "daily", []string{"delta", "Charlie", "Alpha", "bravo", "bucket/failed.rss"}},
+		{sortLatest, "weekly", []string{"Charlie", "delta", "bravo", "Alpha", "bucket/failed.rss"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode.String()+" "+tt.window, func(t *testing.T) {
+			got := slices.Clone(rows)
+			sortRows(got, tt.mode, tt.window, fixedNow)
+			if !slices.Equal(names(got), tt.want) {
+				t.Errorf("sortRows() = %q, want %q", names(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestSortModeNext(t *testing.T) {
+	var got []sortMode
+	for mode, i := sortConfig, 0; i < 5; i++ {
+		mode = mode.next()
+		got = append(got, mode)
+	}
+	if want := []sortMode{sortName, sortRank, sortLatest, sortName, sortRank}; !slices.Equal(got, want) {
+		t.Errorf("next() cycle = %v, want %v", got, want)
+	}
+	if got := rankLabel(seriesMetadata{state: metadataLoaded, data: &SeriesData{Rankings: Rankings{Monthly: 3}}}, "monthly"); got != "#3" {
+		t.Errorf("rankLabel() = %q, want #3", got)
+	}
+}
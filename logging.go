@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// maxLogLines is the number of recent log lines kept for the debug view.
+const maxLogLines = 200
+
+// logBuffer keeps the most recent log lines in memory so the TUI can show
+// them without writing to the terminal.
+type logBuffer struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		b.lines = append(b.lines, line)
+	}
+	if over := len(b.lines) - maxLogLines; over > 0 {
+		b.lines = b.lines[over:]
+	}
+	return len(p), nil
+}
+
+// Tail returns up to n of the most recent lines.
+func (b *logBuffer) Tail(n int) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	start := max(len(b.lines)-n, 0)
+	return append([]string(nil), b.lines[start:]...)
+}
+
+func parseLogLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q", level)
+	}
+	return l, nil
+}
+
+// setupLogging installs the default slog logger. Output goes to the
+// in-memory buffer, to stderr if toStderr is set, and to logFile if given;
+// never to stdout, which belongs to the TUI or to command output. The
+// returned closer releases the log file.
+func setupLogging(level, logFile string, toStderr bool) (*logBuffer, io.Closer, error) {
+	l, err := parseLogLevel(level)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	buf := &logBuffer{}
+	outputs := []io.Writer{buf}
+	if toStderr {
+		outputs = append(outputs, os.Stderr)
+	}
+	var closer io.Closer = io.NopCloser(nil)
+	if logFile != "" {
+		f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		outputs = append(outputs, f)
+		closer = f
+	}
+
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.MultiWriter(outputs...), &slog.HandlerOptions{Level: l})))
+	// Keep the standard logger on stderr for startup errors
+	log.SetOutput(os.Stderr)
+	log.SetFlags(log.LstdFlags)
+
+	return buf, closer, nil
+}
@@ -1,72 +1,204 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
-	"github.com/BurntSushi/toml"
 	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
 type model struct {
+	// ctx is canceled on quit so in-flight fetches and uploads stop
+	ctx    context.Context
+	cancel context.CancelFunc
+
 	series   []Series
 	cursor   int
 	selected map[int]struct{}
 	loading  bool
+	spinner  spinner.Model
 	status   string
+	// history keeps earlier status messages for the history view
+	history statusHistory
+	// activity records which series were generated or uploaded, by GUID
+	activity map[string]seriesActivity
 	s3Client *S3Client
+	s3Err    error
 	settings Settings
+	metadata []seriesMetadata
+	height   int
+	logs     *logBuffer
+	showLogs bool
+
+	// batchAction and batchResults track a running generate/upload-all
+	batchAction  func(Series) seriesResult
+	batchResults batchResult
+	batchDone    int
+	progress     progress.Model
+
+	// sortMode orders the series list
+	sortMode sortMode
+	// adder is the form for adding a series; configFile is where new
+	// series are appended and reloadConfig reads the config back
+	adder        *seriesAdder
+	configFile   string
+	reloadConfig func() (*SeriesConfig, error)
+	// picker lists one series' episodes for a filtered generate
+	picker *episodePicker
+	// pager shows a full-screen view, such as a batch summary, until dismissed
+	pager *pager
 }
 
-func initialModel() model {
-	config, err := loadConfig()
-	if err != nil {
-		log.Fatalf("Error loading config: %v", err)
-	}
+type metadataState int
 
-	s3Client, err := NewS3Client(context.Background())
-	if err != nil {
-		log.Printf("Warning: Failed to initialize S3 client: %v", err)
+const (
+	metadataLoading metadataState = iota
+	metadataLoaded
+	metadataFailed
+)
+
+// seriesMetadata tracks the startup fetch for a single list row.
+type seriesMetadata struct {
+	state metadataState
+	data  *SeriesData
+	err   error
+}
+
+type metadataResult struct {
+	index int
+	data  *SeriesData
+	err   error
+}
+
+func initialModel(ctx context.Context, config *SeriesConfig, logs *logBuffer, configFile string, reloadConfig func() (*SeriesConfig, error)) model {
+	s3Client, s3Err := NewS3Client(ctx, config.Settings)
+	if s3Err != nil {
+		log.Printf("Warning: Failed to initialize S3 client: %v", s3Err)
 	}
 
+	sp := spinner.New()
+	sp.Spinner = spinner.Dot
+	sp.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+
+	ctx, cancel := context.WithCancel(ctx)
 	return model{
+		ctx:      ctx,
+		cancel:   cancel,
 		series:   config.Series,
 		selected: make(map[int]struct{}),
+		activity: make(map[string]seriesActivity),
+		spinner:  sp,
+		progress: progress.New(progress.WithDefaultGradient()),
+		metadata: make([]seriesMetadata, len(config.Series)),
 		s3Client: s3Client,
+		s3Err:    s3Err,
 		settings: config.Settings,
+		logs:     logs,
+
+		configFile:   configFile,
+		reloadConfig: reloadConfig,
 	}
 }
 
-func loadConfig() (*SeriesConfig, error) {
-	configPath := "series.toml"
-	if envPath := os.Getenv("SUMPPI_CONFIG"); envPath != "" {
-		configPath = envPath
+// sortSeries reorders the series and their metadata together, keeping the
+// cursor and selection on the same series.
+func (m model) sortSeries(mode sortMode) model {
+	rows := make([]seriesRow, len(m.series))
+	for i, series := range m.series {
+		rows[i] = seriesRow{series: series, meta: m.metadata[i]}
+	}
+	var current string
+	if len(m.series) > 0 {
+		current = m.series[m.cursor].GUID
 	}
+	selected := make(map[string]bool, len(m.selected))
+	for i := range m.selected {
+		selected[m.series[i].GUID] = true
+	}
+
+	sortRows(rows, mode, m.settings.RankingWindow, time.Now())
 
-	var config SeriesConfig
-	if _, err := toml.DecodeFile(configPath, &config); err != nil {
-		return nil, fmt.Errorf("failed to decode config file: %w", err)
+	m.sortMode = mode
+	m.series = make([]Series, len(rows))
+	m.metadata = make([]seriesMetadata, len(rows))
+	m.selected = make(map[int]struct{}, len(selected))
+	for i, row := range rows {
+		m.series[i], m.metadata[i] = row.series, row.meta
+		if row.series.GUID == current {
+			m.cursor = i
+		}
+		if selected[row.series.GUID] {
+			m.selected[i] = struct{}{}
+		}
 	}
+	return m
+}
 
-	return &config, nil
+// metadataDone counts the series whose startup fetch has finished.
+func (m model) metadataDone() int {
+	done := 0
+	for _, meta := range m.metadata {
+		if meta.state != metadataLoading {
+			done++
+		}
+	}
+	return done
 }
 
 func (m model) Init() tea.Cmd {
-	return nil
+	return m.fetchAllMetadata()
+}
+
+// fetchAllMetadata dispatches one command per series. The commands share a
+// semaphore, so at most metadata_concurrency requests run at once and a slow
+// or failing series does not hold up the rest.
+func (m model) fetchAllMetadata() tea.Cmd {
+	sem := make(chan struct{}, m.settings.metadataConcurrency())
+	cmds := make([]tea.Cmd, len(m.series))
+	for i, series := range m.series {
+		cmds[i] = func() tea.Msg {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			data, err := fetchSeriesData(m.ctx, series.GUID, m.settings)
+			return metadataResult{index: i, data: data, err: err}
+		}
+	}
+	return tea.Batch(cmds...)
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+		m.progress.Width = min(max(msg.Width-4, 10), maxProgressWidth)
 	case tea.KeyMsg:
+		if m.pager != nil {
+			return m.updatePager(msg)
+		}
+		if m.picker != nil {
+			return m.updatePicker(msg)
+		}
+		if m.adder != nil {
+			return m.updateAdder(msg)
+		}
 		switch msg.String() {
 		case "ctrl+c", "q":
-			return m, tea.Quit
+			return m, m.quit()
 		case "up", "k":
 			if m.cursor > 0 {
 				m.cursor--
@@ -77,102 +209,674 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		case "enter", " ":
 			if !m.loading {
-				m.loading = true
-				return m, m.generateFeed()
+				return m.startLoading(m.generateFeed())
+			}
+		case "u", "U", "v", "W", "z":
+			if m.loading {
+				break
 			}
-		case "u":
-			if !m.loading && m.s3Client != nil {
-				m.loading = true
-				return m, m.generateAndUploadFeed()
+			if m.s3Client == nil {
+				m.setStatus(fmt.Sprintf("S3 unavailable: %v (press r to retry)", m.s3Err))
+				break
+			}
+			switch msg.String() {
+			case "u":
+				return m.startLoading(m.generateAndUploadFeed())
+			case "U":
+				return m.uploadAllFeeds()
+			case "v":
+				return m.startLoading(m.showFeedDiff())
+			case "W":
+				return m.startLoading(m.showS3Check())
+			case "z":
+				return m.startLoading(m.undoLastUpload())
+			}
+		case "r":
+			if !m.loading && m.s3Client == nil {
+				return m.startLoading(m.retryS3Client())
+			}
+		case "G":
+			if !m.loading {
+				return m.generateAllFeeds()
 			}
 		case "c":
 			if !m.loading {
 				return m, m.copyURLToClipboard()
 			}
+		case "C":
+			if !m.loading {
+				return m, m.copyAllURLsToClipboard()
+			}
+		case "V":
+			if !m.loading {
+				return m.startLoading(m.showValidation())
+			}
+		case "o":
+			if !m.loading {
+				return m, m.openFeedURL()
+			}
+		case "a":
+			if m.loading {
+				break
+			}
+			// Reloading renumbers the series, which in-flight fetches rely on
+			if m.metadataDone() < len(m.series) {
+				m.setStatus("Wait for metadata to finish loading before adding a series")
+				break
+			}
+			m.adder = &seriesAdder{}
+		case "s":
+			if m.loading {
+				break
+			}
+			// Prefetch results are matched to series by position
+			if m.metadataDone() < len(m.series) {
+				m.setStatus("Wait for metadata to finish loading before sorting")
+				break
+			}
+			m = m.sortSeries(m.sortMode.next())
+			m.setStatus("Sorted by " + m.sortMode.String())
+		case "e":
+			if !m.loading {
+				return m.startLoading(m.openEpisodePicker())
+			}
+		case "J":
+			if !m.loading {
+				return m.startLoading(m.showRawJSON())
+			}
+		case "L":
+			m.showLogs = !m.showLogs
+		case "H":
+			m.pager = newPager("Status History", m.history.lines(), fmt.Sprintf("%d messages", len(m.history)))
+			// Start at the most recent messages
+			m.pager.scroll(len(m.pager.lines), m.pageSize())
 		case "d":
 			if !m.loading {
-				m.loading = true
-				return m, m.showLatestEpisodeDate()
+				return m.startLoading(m.showLatestEpisodeDate())
 			}
+		case "D":
+			if !m.loading {
+				return m.startLoading(m.showAllLatestEpisodes())
+			}
+		}
+	case spinner.TickMsg:
+		// Let the tick chain lapse once loading has finished
+		if m.loading {
+			var cmd tea.Cmd
+			m.spinner, cmd = m.spinner.Update(msg)
+			return m, cmd
+		}
+	case metadataResult:
+		if msg.err != nil {
+			m.metadata[msg.index] = seriesMetadata{state: metadataFailed, err: msg.err}
+		} else {
+			m.metadata[msg.index] = seriesMetadata{state: metadataLoaded, data: msg.data}
 		}
 	case feedResult:
 		m.loading = false
-		m.status = string(msg)
+		m.setStatus(string(msg))
+	case actionResult:
+		m.loading = false
+		recordActivity(m.activity, seriesResult(msg), time.Now())
+		m.setStatus(seriesResult(msg).String())
+	case s3InitResult:
+		m.loading = false
+		m.s3Client, m.s3Err = msg.client, msg.err
+		if msg.err != nil {
+			m.setStatus(fmt.Sprintf("S3 still unavailable: %v", msg.err))
+		} else {
+			m.setStatus("S3 client initialized")
+		}
+	case pagerResult:
+		m.loading = false
+		m.pager = msg
+	case pickerResult:
+		m.loading = false
+		m.picker = msg
+	case adderChecked:
+		if m.adder != nil {
+			m.adder.data, m.adder.err = msg.data, msg.err
+			m.adder.step = adderConfirm
+			if msg.err != nil {
+				m.adder.step = adderGUID
+			} else {
+				m.adder.title = msg.data.Title
+			}
+		}
+	case configReloaded:
+		if msg.err != nil && msg.saved {
+			// Retrying would append the series twice
+			m.adder = nil
+			m.setStatus(fmt.Sprintf("Error: %v", msg.err))
+			break
+		}
+		if msg.err != nil {
+			if m.adder != nil {
+				m.adder.err, m.adder.step = msg.err, adderConfirm
+			}
+			break
+		}
+		m = m.applyConfig(msg.config, msg.guid, msg.added)
+		m.adder = nil
+		m.setStatus(fmt.Sprintf("Added %s to %s", msg.guid, m.configFile))
+	case batchItemResult:
+		m.batchResults[msg.index] = msg.result
+		m.batchDone++
+		recordActivity(m.activity, msg.result, time.Now())
+		if m.batchDone < len(m.series) {
+			return m, nil
+		}
+		m.loading = false
+		m.pager = newPager("Batch Summary", m.batchResults.lines(), m.batchResults.String())
+		m.setStatus(m.batchResults.String())
+		m.batchAction = nil
+		m.batchResults = nil
+		m.batchDone = 0
+	}
+
+	return m, nil
+}
+
+// quit cancels in-flight fetches and uploads, whose results would never be
+// shown, and exits the program.
+func (m model) quit() tea.Cmd {
+	m.cancel()
+	return tea.Quit
+}
+
+// setStatus shows message in the status line and records it in the history.
+func (m *model) setStatus(message string) {
+	m.status = message
+	m.history = m.history.add(message, time.Now())
+}
+
+func (m model) updatePager(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, m.quit()
+	case "esc", "enter", "q":
+		m.pager = nil
+	case "up", "k":
+		m.pager.scroll(-1, m.pageSize())
+	case "down", "j":
+		m.pager.scroll(1, m.pageSize())
+	}
+	return m, nil
+}
+
+func (m model) updateAdder(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	a := m.adder
+	if msg.Type == tea.KeyCtrlC {
+		return m, m.quit()
+	}
+	if msg.Type == tea.KeyEsc {
+		m.adder = nil
+		return m, nil
+	}
+
+	if field := a.input(); field != nil {
+		switch msg.Type {
+		case tea.KeyEnter:
+			a.err = nil
+			if a.step == adderGUID {
+				a.step = adderS3Path
+				break
+			}
+			if err := validateNewSeries(strings.TrimSpace(a.guid), strings.TrimSpace(a.s3Path), m.series); err != nil {
+				a.err = err
+				break
+			}
+			a.guid, a.s3Path = strings.TrimSpace(a.guid), strings.TrimSpace(a.s3Path)
+			a.step = adderChecking
+			return m, m.checkNewSeries(a.guid)
+		case tea.KeyBackspace:
+			if runes := []rune(*field); len(runes) > 0 {
+				*field = string(runes[:len(runes)-1])
+			}
+		case tea.KeyRunes, tea.KeySpace:
+			*field += string(msg.Runes)
+		}
+		return m, nil
+	}
+
+	if a.step == adderConfirm {
+		switch msg.String() {
+		case "y":
+			a.step = adderSaving
+			return m, m.saveNewSeries(a.guid, a.s3Path, a.data)
+		case "n", "q":
+			m.adder = nil
+		}
+	}
+	return m, nil
+}
+
+func (m model) checkNewSeries(guid string) tea.Cmd {
+	return func() tea.Msg {
+		data, err := fetchSeriesData(m.ctx, guid, m.settings)
+		return adderChecked{data: data, err: err}
+	}
+}
+
+func (m model) saveNewSeries(guid, s3Path string, data *SeriesData) tea.Cmd {
+	return func() tea.Msg {
+		if err := appendSeriesToConfig(m.configFile, guid, s3Path); err != nil {
+			return configReloaded{err: err}
+		}
+		config, err := m.reloadConfig()
+		if err != nil {
+			return configReloaded{saved: true, err: fmt.Errorf("series added, but reloading the config failed: %w", err)}
+		}
+		return configReloaded{config: config, guid: guid, added: data}
+	}
+}
+
+// applyConfig switches to a reloaded config, keeping fetched metadata by
+// GUID and using the lookup done while adding for the new series.
+func (m model) applyConfig(config *SeriesConfig, guid string, added *SeriesData) model {
+	byGUID := make(map[string]seriesMetadata, len(m.series))
+	for i, series := range m.series {
+		byGUID[series.GUID] = m.metadata[i]
+	}
+	byGUID[guid] = seriesMetadata{state: metadataLoaded, data: added}
+
+	m.series = config.Series
+	m.settings = config.Settings
+	m.metadata = make([]seriesMetadata, len(config.Series))
+	for i, series := range config.Series {
+		meta, ok := byGUID[series.GUID]
+		if !ok {
+			meta = seriesMetadata{state: metadataFailed, err: fmt.Errorf("not loaded")}
+		}
+		m.metadata[i] = meta
+	}
+	m.selected = make(map[int]struct{})
+	m.cursor = min(m.cursor, max(len(m.series)-1, 0))
+	// Keep activity only for series that are still configured
+	activity := make(map[string]seriesActivity, len(m.activity))
+	for _, series := range config.Series {
+		if a, ok := m.activity[series.GUID]; ok {
+			activity[series.GUID] = a
+		}
+	}
+	m.activity = activity
+	// The reloaded list is in config order
+	m.sortMode = sortConfig
+	return m
+}
+
+func (m model) updatePicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.picker
+	if msg.Type == tea.KeyCtrlC {
+		return m, m.quit()
+	}
+	if p.typing {
+		switch msg.Type {
+		case tea.KeyEsc:
+			p.setFilter("")
+			p.typing = false
+		case tea.KeyEnter:
+			p.typing = false
+		case tea.KeyBackspace:
+			if runes := []rune(p.filter); len(runes) > 0 {
+				p.setFilter(string(runes[:len(runes)-1]))
+			}
+		case tea.KeyRunes, tea.KeySpace:
+			p.setFilter(p.filter + string(msg.Runes))
+		}
+		return m, nil
 	}
 
+	switch msg.String() {
+	case "esc", "q":
+		m.picker = nil
+	case "up", "k":
+		p.move(-1, m.pageSize()-2)
+	case "down", "j":
+		p.move(1, m.pageSize()-2)
+	case "/":
+		p.typing = true
+	case " ":
+		p.toggle()
+	case "a":
+		p.toggleAll()
+	case "enter":
+		episodes := p.chosen()
+		if len(episodes) == 0 {
+			break
+		}
+		m.picker = nil
+		return m.startLoading(func() tea.Msg {
+			return actionResult(generateEpisodes(m.ctx, p.series, m.settings, p.data, episodes))
+		})
+	}
 	return m, nil
 }
 
+// pageSize is the number of pager lines that fit on screen.
+func (m model) pageSize() int {
+	if m.height <= 6 {
+		return 10
+	}
+	return m.height - 6
+}
+
+func (b batchResult) String() string {
+	failed, skipped := 0, 0
+	for _, r := range b {
+		switch {
+		case r.skipped():
+			skipped++
+		case r.Err != nil:
+			failed++
+		}
+	}
+	return fmt.Sprintf("Batch finished: %d succeeded, %d failed, %d skipped", len(b)-failed-skipped, failed, skipped)
+}
+
+// lines renders one styled row per series for the summary pager.
+func (b batchResult) lines() []string {
+	okStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	skippedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+
+	lines := make([]string, len(b))
+	for i, r := range b {
+		if r.skipped() {
+			lines[i] = skippedStyle.Render("- "+r.GUID) + " skipped: " + r.Err.Error()
+		} else if r.Err != nil {
+			lines[i] = errorStyle.Render("✗ "+r.GUID) + " " + r.Err.Error()
+		} else {
+			lines[i] = okStyle.Render("✓ "+r.GUID) + " " + r.String()
+		}
+	}
+	return lines
+}
+
+func (m model) startLoading(cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	m.loading = true
+	return m, tea.Batch(cmd, m.spinner.Tick)
+}
+
 type feedResult string
 
+// s3InitResult reports the outcome of retrying S3 client initialization.
+type s3InitResult struct {
+	client *S3Client
+	err    error
+}
+
+func (m model) retryS3Client() tea.Cmd {
+	return func() tea.Msg {
+		client, err := NewS3Client(m.ctx, m.settings)
+		return s3InitResult{client: client, err: err}
+	}
+}
+
+// adderChecked reports the upstream lookup of the series being added.
+type adderChecked struct {
+	data *SeriesData
+	err  error
+}
+
+// configReloaded carries the config read back after adding a series.
+type configReloaded struct {
+	config *SeriesConfig
+	guid   string
+	added  *SeriesData
+	// saved is set when the series was written even if reloading failed
+	saved bool
+	err   error
+}
+
+// actionResult is the outcome of generating, uploading or restoring the
+// feed of a single series.
+type actionResult seriesResult
+
+// pickerResult opens the episode picker for a fetched series.
+type pickerResult *episodePicker
+
+// pagerResult opens a full-screen pager with the finished command's output.
+type pagerResult *pager
+
+// batchResult holds the per-series outcomes of a generate/upload-all run.
+type batchResult []seriesResult
+
+// batchItemResult reports the outcome for one series of a batch run. Results
+// arrive in completion order, so index places them back in config order.
+type batchItemResult struct {
+	index  int
+	result seriesResult
+}
+
+// maxProgressWidth caps the batch progress bar on wide terminals.
+const maxProgressWidth = 60
+
 func (m model) generateFeed() tea.Cmd {
+	return func() tea.Msg {
+		return actionResult(generateSeries(m.ctx, m.series[m.cursor], m.settings))
+	}
+}
+
+func (m model) generateAndUploadFeed() tea.Cmd {
+	return func() tea.Msg {
+		return actionResult(uploadSeries(m.ctx, m.s3Client, m.series[m.cursor], m.settings))
+	}
+}
+
+// startBatch applies action to every series, running at most
+// batch_concurrency of them at once and reporting each result so progress
+// can be shown. Failures, including panics, do not stop the batch.
+func (m model) startBatch(action func(Series) seriesResult) (tea.Model, tea.Cmd) {
+	if len(m.series) == 0 {
+		return m, nil
+	}
+	m.batchAction = action
+	m.batchResults = make(batchResult, len(m.series))
+	m.batchDone = 0
+
+	sem := make(chan struct{}, m.settings.batchConcurrency())
+	cmds := make([]tea.Cmd, len(m.series))
+	for i, series := range m.series {
+		cmds[i] = func() tea.Msg {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			return batchItemResult{index: i, result: runAction(action, series)}
+		}
+	}
+	return m.startLoading(tea.Batch(cmds...))
+}
+
+func (m model) generateAllFeeds() (tea.Model, tea.Cmd) {
+	return m.startBatch(func(series Series) seriesResult {
+		return generateSeries(m.ctx, series, m.settings)
+	})
+}
+
+func (m model) uploadAllFeeds() (tea.Model, tea.Cmd) {
+	return m.startBatch(func(series Series) seriesResult {
+		return uploadSeries(m.ctx, m.s3Client, series, m.settings)
+	})
+}
+
+// undoLastUpload restores the previous version of the selected feed.
+func (m model) undoLastUpload() tea.Cmd {
+	return func() tea.Msg {
+		return actionResult(undoUpload(m.ctx, m.s3Client, m.series[m.cursor]))
+	}
+}
+
+func (m model) copyURLToClipboard() tea.Cmd {
 	return func() tea.Msg {
 		series := m.series[m.cursor]
 
-		seriesData, err := fetchSeriesData(series.GUID)
+		url, err := generateS3URL(series.S3Path, m.settings)
 		if err != nil {
-			return feedResult(fmt.Sprintf("Error fetching series data: %v", err))
+			return feedResult(fmt.Sprintf("Error generating URL: %v", err))
 		}
 
-		rssXML, err := generateRSSFeed(seriesData, false, m.settings)
+		err = clipboard.WriteAll(url)
 		if err != nil {
-			return feedResult(fmt.Sprintf("Error generating RSS feed: %v", err))
+			return feedResult(fmt.Sprintf("Error copying to clipboard: %v", err))
+		}
+
+		return feedResult(fmt.Sprintf("URL copied to clipboard: %s", url))
+	}
+}
+
+// copyAllURLsToClipboard copies the public URL of every series, one per
+// line. Series with invalid S3 paths are left out and named in the status.
+func (m model) copyAllURLsToClipboard() tea.Cmd {
+	return func() tea.Msg {
+		var urls, invalid []string
+		for _, series := range m.series {
+			url, err := generateS3URL(series.S3Path, m.settings)
+			if err != nil {
+				invalid = append(invalid, series.GUID)
+				continue
+			}
+			urls = append(urls, url)
+		}
+
+		if err := clipboard.WriteAll(strings.Join(urls, "\n")); err != nil {
+			return feedResult(fmt.Sprintf("Error copying to clipboard: %v", err))
 		}
 
-		// Extract filename from S3 path
-		filename := filepath.Base(series.S3Path)
-		if !strings.HasSuffix(filename, ".rss") {
-			filename = fmt.Sprintf("%s.rss", series.GUID)
+		msg := fmt.Sprintf("Copied %d URLs to clipboard", len(urls))
+		if len(invalid) > 0 {
+			msg += fmt.Sprintf("; skipped %d with invalid S3 paths: %s", len(invalid), strings.Join(invalid, ", "))
 		}
+		return feedResult(msg)
+	}
+}
+
+// openFeedURL opens the public feed URL in the browser, falling back to the
+// clipboard when no browser command is available.
+func (m model) openFeedURL() tea.Cmd {
+	return func() tea.Msg {
+		series := m.series[m.cursor]
 
-		err = os.WriteFile(filename, []byte(rssXML), 0644)
+		url, err := generateS3URL(series.S3Path, m.settings)
 		if err != nil {
-			return feedResult(fmt.Sprintf("Error writing RSS file: %v", err))
+			return feedResult(fmt.Sprintf("Error generating URL: %v", err))
 		}
 
-		return feedResult(fmt.Sprintf("RSS feed written to %s (%s by %s, %d episodes)", filename, seriesData.Title, seriesData.Author, len(seriesData.Episodes)))
+		if err := openInBrowser(url); err != nil {
+			if clipErr := clipboard.WriteAll(url); clipErr != nil {
+				return feedResult(fmt.Sprintf("Error opening browser: %v", err))
+			}
+			return feedResult(fmt.Sprintf("Could not open browser (%v), URL copied to clipboard: %s", err, url))
+		}
+
+		return feedResult(fmt.Sprintf("Opened %s", url))
 	}
 }
 
-func (m model) generateAndUploadFeed() tea.Cmd {
+// showFeedDiff compares the live feed on S3 with a freshly generated one.
+func (m model) showFeedDiff() tea.Cmd {
 	return func() tea.Msg {
 		series := m.series[m.cursor]
 
-		seriesData, err := fetchSeriesData(series.GUID)
+		result := seriesResult{GUID: series.GUID}
+		_, rssXML, err := buildSeriesFeed(m.ctx, series, m.settings, &result)
 		if err != nil {
-			return feedResult(fmt.Sprintf("Error fetching series data: %v", err))
+			return feedResult(fmt.Sprintf("Error: %v", err))
+		}
+
+		title := "Changes to " + displayPath(series.S3Path)
+		existing, err := m.s3Client.GetRSS(m.ctx, series.S3Path)
+		if errors.Is(err, ErrFeedNotFound) {
+			return pagerResult(newPager(title, []string{"new feed (no existing object)"}, ""))
+		}
+		if err != nil {
+			return feedResult(fmt.Sprintf("Error fetching live feed: %v", err))
 		}
 
-		rssXML, err := generateRSSFeed(seriesData, false, m.settings)
+		lines, err := diffFeeds(existing, rssXML)
 		if err != nil {
-			return feedResult(fmt.Sprintf("Error generating RSS feed: %v", err))
+			return feedResult(fmt.Sprintf("Error comparing feeds: %v", err))
 		}
+		return pagerResult(newPager(title, lines, ""))
+	}
+}
+
+func (m model) showValidation() tea.Cmd {
+	return func() tea.Msg {
+		series := m.series[m.cursor]
 
-		// Upload directly to S3 from memory
-		err = m.s3Client.UploadRSSContent(context.Background(), rssXML, series.S3Path)
+		issues, err := validateSeries(m.ctx, series, m.settings)
 		if err != nil {
-			return feedResult(fmt.Sprintf("Error uploading to S3: %v", err))
+			return feedResult(fmt.Sprintf("Error: %v", err))
 		}
 
-		return feedResult(fmt.Sprintf("RSS feed uploaded to %s (%s by %s, %d episodes)", series.S3Path, seriesData.Title, seriesData.Author, len(seriesData.Episodes)))
+		lines := []string{"no issues found"}
+		if len(issues) > 0 {
+			lines = make([]string, len(issues))
+			for i, issue := range issues {
+				lines[i] = issue.String()
+			}
+		}
+		return pagerResult(newPager("Validation of "+displayPath(series.S3Path), lines, ""))
 	}
 }
 
-func (m model) copyURLToClipboard() tea.Cmd {
+func (m model) openEpisodePicker() tea.Cmd {
 	return func() tea.Msg {
 		series := m.series[m.cursor]
 
-		url, err := generateS3URL(series.S3Path)
+		seriesData, err := fetchSeriesData(m.ctx, series.GUID, m.settings)
 		if err != nil {
-			return feedResult(fmt.Sprintf("Error generating URL: %v", err))
+			return feedResult(fmt.Sprintf("Error: %v", err))
 		}
+		return pickerResult(newEpisodePicker(series, seriesData))
+	}
+}
 
-		err = clipboard.WriteAll(url)
+// maxRawJSONLines caps the raw API view so huge payloads stay responsive.
+const maxRawJSONLines = 5000
+
+// showRawJSON displays the pretty-printed API response for the series.
+func (m model) showRawJSON() tea.Cmd {
+	return func() tea.Msg {
+		series := m.series[m.cursor]
+
+		raw, err := fetchSeriesJSON(m.ctx, series.GUID, m.settings)
 		if err != nil {
-			return feedResult(fmt.Sprintf("Error copying to clipboard: %v", err))
+			return feedResult(fmt.Sprintf("Error: %v", err))
 		}
 
-		return feedResult(fmt.Sprintf("URL copied to clipboard: %s", url))
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, raw, "", "  "); err != nil {
+			return feedResult(fmt.Sprintf("Error: API response is not valid JSON: %v", err))
+		}
+
+		lines := strings.Split(pretty.String(), "\n")
+		footer := fmt.Sprintf("%d bytes", len(raw))
+		if len(lines) > maxRawJSONLines {
+			footer += fmt.Sprintf(", truncated: %d more lines not shown", len(lines)-maxRawJSONLines)
+			lines = append(lines[:maxRawJSONLines], "…")
+		}
+		return pagerResult(newPager("API response for "+series.GUID, lines, footer))
+	}
+}
+
+// showS3Check tests write access to every configured S3 path.
+func (m model) showS3Check() tea.Cmd {
+	return func() tea.Msg {
+		okStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+		errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+
+		var lines []string
+		failed := 0
+		for _, check := range checkS3Paths(m.ctx, m.s3Client, m.series) {
+			if check.Err != nil {
+				failed++
+				lines = append(lines, errorStyle.Render("✗ "+check.Path)+" "+check.Err.Error())
+			} else {
+				lines = append(lines, okStyle.Render("✓ "+check.Path)+" writable")
+			}
+		}
+		return pagerResult(newPager("S3 Access Check", lines, fmt.Sprintf("%d of %d paths failed", failed, len(lines))))
 	}
 }
 
@@ -180,12 +884,12 @@ func (m model) showLatestEpisodeDate() tea.Cmd {
 	return func() tea.Msg {
 		series := m.series[m.cursor]
 
-		seriesData, err := fetchSeriesData(series.GUID)
+		seriesData, err := fetchSeriesData(m.ctx, series.GUID, m.settings)
 		if err != nil {
 			return feedResult(fmt.Sprintf("Error fetching series data: %v", err))
 		}
 
-		latestDate, err := getLatestEpisodeDate(seriesData.Episodes)
+		latestDate, err := getLatestEpisodeDate(seriesData.Episodes, time.Now())
 		if err != nil {
 			return feedResult(fmt.Sprintf("Error finding latest episode: %v", err))
 		}
@@ -194,13 +898,38 @@ func (m model) showLatestEpisodeDate() tea.Cmd {
 	}
 }
 
+func (m model) showAllLatestEpisodes() tea.Cmd {
+	return func() tea.Msg {
+		now := time.Now()
+		rows := fetchLatestEpisodes(m.ctx, m.series, m.settings, now)
+		return feedResult(formatLatestTable(rows, now))
+	}
+}
+
 func (m model) View() string {
+	if m.pager != nil {
+		return m.pager.view(m.pageSize())
+	}
+	if m.picker != nil {
+		return m.picker.view(m.pageSize())
+	}
+	if m.adder != nil {
+		return m.adder.view()
+	}
+
 	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("205"))
 	selectedStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("170"))
 	normalStyle := lipgloss.NewStyle()
 	statusStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	staleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
 
-	s := headerStyle.Render("RSS Feed Generator") + "\n\n"
+	now := time.Now()
+	s := headerStyle.Render("RSS Feed Generator") + statusStyle.Render(" "+version)
+	if loaded := m.metadataDone(); loaded < len(m.series) {
+		s += statusStyle.Render(fmt.Sprintf(" • loading metadata %d/%d", loaded, len(m.series)))
+	}
+	s += "\n\n"
 	s += "Select a series to generate RSS feed:\n\n"
 
 	for i, series := range m.series {
@@ -209,7 +938,21 @@ func (m model) View() string {
 			cursor = ">"
 		}
 
-		line := fmt.Sprintf("%s %s", cursor, extractFilename(series.S3Path))
+		line := fmt.Sprintf("%s %s %s", cursor, m.activity[series.GUID].state.glyph(), displayPath(series.S3Path))
+		switch meta := m.metadata[i]; meta.state {
+		case metadataLoading:
+			line += statusStyle.Render(" (loading...)")
+		case metadataLoaded:
+			line += " — " + meta.data.Title
+			if rank := rankLabel(meta, m.settings.RankingWindow); rank != "" {
+				line += " " + statusStyle.Render(rank)
+			}
+			if latest, err := latestEpisodeTime(meta.data.Episodes, now); err == nil && isStale(latest, now, m.settings.staleThreshold()) {
+				line += " " + staleStyle.Render("●")
+			}
+		case metadataFailed:
+			line += statusStyle.Render(fmt.Sprintf(" (error: %v)", meta.err))
+		}
 		if m.cursor == i {
 			line = selectedStyle.Render(line)
 		} else {
@@ -218,31 +961,208 @@ func (m model) View() string {
 		s += line + "\n"
 	}
 
-	s3Status := ""
-	if m.s3Client != nil {
-		s3Status = " • u: upload to S3"
+	// Show where the feed under the cursor is served from, as c would copy it
+	if len(m.series) > 0 {
+		if url, err := generateS3URL(m.series[m.cursor].S3Path, m.settings); err != nil {
+			s += "\n" + errorStyle.Render(fmt.Sprintf("URL: %v", err)) + "\n"
+		} else {
+			s += "\n" + statusStyle.Render("URL: "+url) + "\n"
+		}
+		if a, ok := m.activity[m.series[m.cursor].GUID]; ok {
+			s += statusStyle.Render(fmt.Sprintf("%s at %s", a.state, a.at.Format("15:04:05"))) + "\n"
+		}
+	}
+
+	s3Status := " • u: upload to S3 • U: upload all • v: diff live feed • W: check S3 access • z: undo upload"
+	if m.s3Client == nil {
+		s3Status = " • " + errorStyle.Render(fmt.Sprintf("S3 unavailable: %v", m.s3Err)) + statusStyle.Render(" • r: retry S3")
 	}
-	s += "\n" + statusStyle.Render(fmt.Sprintf("j/k: navigate • enter/space: generate feed%s • d: show latest episode • c: copy URL • q: quit", s3Status))
+	s += "\n" + statusStyle.Render(fmt.Sprintf("j/k: navigate • enter/space: generate feed • e: pick episodes • a: add series • s: sort%s • G: generate all • d/D: latest episode (all) • c/C: copy URL (all) • o: open URL • V: validate • J: raw JSON • L: logs • H: history • q: quit", s3Status))
 
-	if m.loading {
-		s += "\n\n" + statusStyle.Render("Generating feed...")
+	if m.batchAction != nil {
+		done := m.batchDone
+		s += "\n\n" + m.progress.ViewAs(float64(done)/float64(len(m.series))) +
+			statusStyle.Render(fmt.Sprintf(" %d/%d", done, len(m.series)))
+	} else if m.loading {
+		s += "\n\n" + m.spinner.View() + " " + statusStyle.Render("Generating feed...")
 	} else if m.status != "" {
 		s += "\n\n" + statusStyle.Render(m.status)
 	}
 
+	if m.showLogs {
+		s += "\n\n" + headerStyle.Render("Recent log") + "\n"
+		for _, line := range m.logs.Tail(logViewLines) {
+			s += statusStyle.Render(line) + "\n"
+		}
+	}
+
 	return s
 }
 
-func extractFilename(s3Path string) string {
-	filename := filepath.Base(s3Path)
-	if filename == "." || filename == "/" {
-		return s3Path
+// logViewLines is the number of log lines shown in the debug view.
+const logViewLines = 15
+
+// exitNoConfig is the exit status when the config file does not exist.
+const exitNoConfig = 3
+
+// offerConfigTemplate handles a missing config file. Interactively it asks
+// to create a template at the expected path; otherwise it prints the
+// template to stderr so scripts fail with a usable hint.
+func offerConfigTemplate(notFound *ConfigNotFoundError, interactive bool) int {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", notFound)
+	if !interactive {
+		fmt.Fprintf(os.Stderr, "\nSave the following template as %s and edit it:\n\n%s", notFound.Path, configTemplate)
+		return exitNoConfig
+	}
+
+	fmt.Fprintf(os.Stderr, "Create a template config at %s? [y/N] ", notFound.Path)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if !strings.EqualFold(strings.TrimSpace(answer), "y") {
+		return exitNoConfig
 	}
-	return filename
+
+	if err := writeConfigTemplate(notFound.Path); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s; add your series and run sumppi again.\n", notFound.Path)
+	return exitNoConfig
 }
 
 func main() {
-	p := tea.NewProgram(initialModel())
+	configPath := flag.String("config", "", "path to the series config (default $SUMPPI_CONFIG or series.toml)")
+	configDir := flag.String("config-dir", "", "load and merge every *.toml file in this directory instead of a single config")
+	logLevel := flag.String("log-level", "info", "log level: debug, info, warn or error")
+	logFile := flag.String("log-file", "", "append logs to this file")
+	awsProfile := flag.String("profile", "", "AWS profile to use, overriding aws_profile in the config")
+	staleCheck := flag.Bool("check-stale", false, "list stale feeds and exit non-zero if there are any")
+	s3Check := flag.Bool("check-s3", false, "check every configured S3 path is writable and exit non-zero on failures")
+	aclAudit := flag.Bool("check-acl", false, "check every uploaded feed is publicly readable and exit non-zero on mismatches")
+	validateGUID := flag.String("validate", "", "validate the feed for this series GUID and exit non-zero on errors")
+	episodesGUID := flag.String("episodes", "", "list the episodes of this series GUID and exit")
+	jsonOutput := flag.Bool("json", false, "print one JSON object per series (or episode, with --episodes) in non-interactive mode")
+	toStdout := flag.Bool("stdout", false, "with generate and a single guid, print the feed XML to stdout instead of writing a file")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [generate|upload [guid...] | undo guid... | latest | opml]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	watchInterval := flag.Duration("watch", 0, "upload every series at this interval (e.g. 15m) until interrupted")
+	showVersion := flag.Bool("version", false, "print version information and exit")
+	flag.Parse()
+
+	// Checked before loading the config so it works without one
+	if *showVersion {
+		fmt.Println(versionString())
+		return
+	}
+
+	// Only the TUI owns the terminal; every other mode logs to stderr
+	interactive := flag.NArg() == 0 && *watchInterval == 0 && !*s3Check && !*aclAudit && !*staleCheck && !*toStdout && *validateGUID == "" && *episodesGUID == ""
+
+	logs, closer, err := setupLogging(*logLevel, *logFile, !interactive)
+	if err != nil {
+		log.Fatalf("Error setting up logging: %v", err)
+	}
+	defer closer.Close()
+
+	if *configPath != "" && *configDir != "" {
+		log.Fatalf("Error: --config and --config-dir cannot be used together")
+	}
+
+	var config *SeriesConfig
+	if *configDir != "" {
+		config, err = loadConfigDir(*configDir)
+	} else {
+		config, err = loadConfig(*configPath)
+	}
+	var notFound *ConfigNotFoundError
+	if errors.As(err, &notFound) {
+		code := offerConfigTemplate(notFound, interactive)
+		closer.Close()
+		os.Exit(code)
+	}
+	if err != nil {
+		log.Fatalf("Error loading config: %v", err)
+	}
+	if *awsProfile != "" {
+		config.Settings.AWSProfile = *awsProfile
+	}
+
+	if *validateGUID != "" {
+		code := runValidate(config, *validateGUID)
+		closer.Close()
+		os.Exit(code)
+	}
+
+	if *episodesGUID != "" {
+		code := printEpisodes(config, *episodesGUID, *jsonOutput)
+		closer.Close()
+		os.Exit(code)
+	}
+
+	if *watchInterval > 0 {
+		code := runWatch(config, *watchInterval)
+		closer.Close()
+		os.Exit(code)
+	}
+
+	if *s3Check {
+		code := runS3Check(config)
+		closer.Close()
+		os.Exit(code)
+	}
+
+	if *aclAudit {
+		code := runACLCheck(config)
+		closer.Close()
+		os.Exit(code)
+	}
+
+	if *staleCheck {
+		code := checkStale(config)
+		closer.Close()
+		os.Exit(code)
+	}
+
+	if *toStdout {
+		if flag.NArg() != 2 || flag.Arg(0) != "generate" {
+			fmt.Fprintln(os.Stderr, "Error: --stdout requires exactly: generate <guid>")
+			closer.Close()
+			os.Exit(2)
+		}
+		code := printFeed(config, flag.Arg(1))
+		closer.Close()
+		os.Exit(code)
+	}
+
+	// A command argument selects non-interactive mode
+	if flag.NArg() > 0 {
+		code := runHeadless(config, flag.Arg(0), flag.Args()[1:], *jsonOutput)
+		closer.Close()
+		os.Exit(code)
+	}
+
+	// New series are appended to the config file, or to a file of their
+	// own in a config directory
+	configFile := resolveConfigPath(*configPath)
+	reloadConfig := func() (*SeriesConfig, error) { return loadConfig(*configPath) }
+	if *configDir != "" {
+		configFile = filepath.Join(*configDir, addedSeriesFile)
+		reloadConfig = func() (*SeriesConfig, error) { return loadConfigDir(*configDir) }
+	}
+	if *awsProfile != "" {
+		// Keep the --profile override when the config is read back
+		load := reloadConfig
+		reloadConfig = func() (*SeriesConfig, error) {
+			config, err := load()
+			if err == nil {
+				config.Settings.AWSProfile = *awsProfile
+			}
+			return config, err
+		}
+	}
+
+	p := tea.NewProgram(initialModel(context.Background(), config, logs, configFile, reloadConfig))
 	if _, err := p.Run(); err != nil {
 		log.Fatalf("Error running program: %v", err)
 	}
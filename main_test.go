@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// TestMain lets tests run the real main in a subprocess: runMain re-executes
+// the test binary with SUMPPI_RUN_MAIN set.
+func TestMain(m *testing.M) {
+	if os.Getenv("SUMPPI_RUN_MAIN") == "1" {
+		os.Args = append([]string{"sumppi"}, os.Args[1:]...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runMain runs sumppi with args in dir and returns its stdout, stderr and
+// exit status.
+func runMain(t *testing.T, dir string, args ...string) (string, string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "SUMPPI_RUN_MAIN=1", "SUMPPI_CONFIG=")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		t.Fatalf("failed to run main: %v", err)
+	}
+	return stdout.String(), stderr.String(), cmd.ProcessState.ExitCode()
+}
+
+func TestVersionFlagWithoutConfig(t *testing.T) {
+	stdout, stderr, code := runMain(t, t.TempDir(), "-version")
+	if code != 0 {
+		t.Fatalf("sumppi -version exited %d: %s", code, stderr)
+	}
+	if !strings.HasPrefix(stdout, "sumppi "+version+" (commit ") {
+		t.Errorf("stdout = %q, want the version line", stdout)
+	}
+}
+
+// runCmd runs cmd and any commands it batches concurrently, as the Bubble
+// Tea runtime would, and returns the resulting messages.
+func runCmd(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	batch, ok := cmd().(tea.BatchMsg)
+	if !ok {
+		return []tea.Msg{cmd()}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var msgs []tea.Msg
+	for _, c := range batch {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got := runCmd(c)
+			mu.Lock()
+			msgs = append(msgs, got...)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return msgs
+}
+
+// peakCounter records the largest number of concurrent callers of enter.
+type peakCounter struct {
+	inFlight, peak atomic.Int32
+}
+
+// enter marks a request as running and returns the function that ends it.
+func (c *peakCounter) enter() func() {
+	n := c.inFlight.Add(1)
+	for {
+		peak := c.peak.Load()
+		if n <= peak || c.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	return func() { c.inFlight.Add(-1) }
+}
+
+func TestUploadAllFeedsPool(t *testing.T) {
+	var counter peakCounter
+	settings := apiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		defer counter.enter()()
+		time.Sleep(20 * time.Millisecond)
+
+		guid := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), ".json")
+		switch guid {
+		case "missing":
+			http.NotFound(w, r)
+		case "broken":
+			http.Error(w, "boom", http.StatusInternalServerError)
+		default:
+			w.Write([]byte(`{"data": {"guid": "` + guid + `", "title": "Show"}}`))
+		}
+	})
+	settings.BatchConcurrency = 2
+
+	var series []Series
+	for _, guid := range []string{"a", "b", "missing", "c", "broken", "d"} {
+		series = append(series, Series{GUID: guid, S3Path: "s3://bucket/" + guid + ".rss"})
+	}
+	fake := newFakeS3()
+	m := model{
+		ctx:      context.Background(),
+		series:   series,
+		settings: settings,
+		s3Client: fake.client(),
+		activity: make(map[string]seriesActivity),
+	}
+
+	next, cmd := m.uploadAllFeeds()
+	m = next.(model)
+	for _, msg := range runCmd(cmd) {
+		if _, ok := msg.(batchItemResult); !ok {
+			continue
+		}
+		next, _ := m.Update(msg)
+		m = next.(model)
+	}
+
+	if got := counter.peak.Load(); got > 2 {
+		t.Errorf("%d series ran at once, want at most batch_concurrency 2", got)
+	}
+	if m.loading {
+		t.Error("model is still loading after every series reported")
+	}
+	if want := "Batch finished: 4 succeeded, 1 failed, 1 skipped"; m.status != want {
+		t.Errorf("status = %q, want %q", m.status, want)
+	}
+	if len(fake.objects) != 4 {
+		t.Errorf("uploaded %d feeds, want 4", len(fake.objects))
+	}
+}
+
+func TestMissingConfigHeadless(t *testing.T) {
+	dir := t.TempDir()
+	stdout, stderr, code := runMain(t, dir, "generate")
+	if code != exitNoConfig {
+		t.Errorf("exit status = %d, want %d", code, exitNoConfig)
+	}
+	if stdout != "" {
+		t.Errorf("stdout = %q, want nothing", stdout)
+	}
+	if !strings.Contains(stderr, defaultConfigPath+" not found") || !strings.Contains(stderr, configTemplate) {
+		t.Errorf("stderr = %q, want the not-found error and the template", stderr)
+	}
+	if _, err := os.Stat(filepath.Join(dir, defaultConfigPath)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("headless mode created a config file: %v", err)
+	}
+}
+
+func TestFetchAllMetadataBounded(t *testing.T) {
+	var counter peakCounter
+	settings := apiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		defer counter.enter()()
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(`{"data": {"guid": "abc", "title": "Show"}}`))
+	})
+	settings.MetadataConcurrency = 2
+
+	series := make([]Series, 6)
+	m := model{
+		ctx:      context.Background(),
+		series:   series,
+		settings: settings,
+		metadata: make([]seriesMetadata, len(series)),
+	}
+	if view := m.View(); !strings.Contains(view, "loading metadata 0/6") {
+		t.Errorf("header does not show the prefetch progress:\n%s", view)
+	}
+
+	for _, msg := range runCmd(m.fetchAllMetadata()) {
+		next, _ := m.Update(msg)
+		m = next.(model)
+	}
+
+	if got := counter.peak.Load(); got > 2 {
+		t.Errorf("%d fetches ran at once, want at most metadata_concurrency 2", got)
+	}
+	if got := m.metadataDone(); got != len(series) {
+		t.Errorf("metadataDone() = %d, want %d", got, len(series))
+	}
+	if view := m.View(); strings.Contains(view, "loading metadata") {
+		t.Errorf("header still shows the prefetch progress:\n%s", view)
+	}
+}
+
+func TestQuitCancelsSlowFetch(t *testing.T) {
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	settings := apiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	})
+	settings.OutputDir = t.TempDir()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m := model{
+		ctx:      ctx,
+		cancel:   cancel,
+		series:   []Series{{GUID: "slow", S3Path: "s3://bucket/slow.rss"}},
+		settings: settings,
+	}
+	done := make(chan tea.Msg, 1)
+	go func() { done <- m.generateFeed()() }()
+
+	time.Sleep(20 * time.Millisecond)
+	m.quit()
+	select {
+	case msg := <-done:
+		result := seriesResult(msg.(actionResult))
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("result error = %v, want context.Canceled", result.Err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("fetch did not stop after quit")
+	}
+
+	entries, err := os.ReadDir(settings.OutputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("output directory has %d entries after quit, want none", len(entries))
+	}
+}
+
+func TestWriteSeriesFeedAfterCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	settings := Settings{OutputDir: t.TempDir()}
+
+	result := writeSeriesFeed(ctx, Series{GUID: "abc", S3Path: "s3://bucket/show.rss"}, settings, "<rss/>", seriesResult{})
+	if !errors.Is(result.Err, context.Canceled) {
+		t.Errorf("writeSeriesFeed() error = %v, want context.Canceled", result.Err)
+	}
+	if entries, _ := os.ReadDir(settings.OutputDir); len(entries) != 0 {
+		t.Errorf("a feed was written after cancellation: %v", entries)
+	}
+}
+
+func TestPickerCtrlCWhileFiltering(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	picker := newEpisodePicker(Series{GUID: "show"}, &SeriesData{Episodes: []Episode{{GUID: "ep-1", Title: "One"}}})
+	picker.typing = true
+	m := model{ctx: ctx, cancel: cancel, picker: picker}
+
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	if cmd == nil {
+		t.Fatal("ctrl+c while filtering returned no command")
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Error("ctrl+c while filtering did not quit")
+	}
+	if ctx.Err() == nil {
+		t.Error("ctrl+c while filtering did not cancel in-flight work")
+	}
+	if picker.filter != "" {
+		t.Errorf("ctrl+c was typed into the filter: %q", picker.filter)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"path"
+	"sync"
+	"time"
+)
+
+// opmlFilename is the local name of the generated index.
+const opmlFilename = "feeds.opml"
+
+type opmlDocument struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    opmlHead `xml:"head"`
+	Body    opmlBody `xml:"body"`
+}
+
+type opmlHead struct {
+	Title       string `xml:"title"`
+	DateCreated string `xml:"dateCreated"`
+}
+
+type opmlBody struct {
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+type opmlOutline struct {
+	Type   string `xml:"type,attr"`
+	Text   string `xml:"text,attr"`
+	Title  string `xml:"title,attr"`
+	XMLURL string `xml:"xmlUrl,attr"`
+}
+
+// seriesTitles fetches the title of every series, falling back to the feed
+// filename for series whose metadata cannot be fetched.
+func seriesTitles(series []Series, settings Settings) []string {
+	titles := make([]string, len(series))
+	sem := make(chan struct{}, settings.metadataConcurrency())
+	var wg sync.WaitGroup
+
+	for i, s := range series {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			seriesData, err := fetchSeriesData(context.Background(), s.GUID, settings)
+			if err != nil || seriesData.Title == "" {
+				slog.Warn("using filename as OPML title", "guid", s.GUID, "err", err)
+				titles[i] = path.Base(s.S3Path)
+				return
+			}
+			titles[i] = seriesData.Title
+		}()
+	}
+	wg.Wait()
+
+	return titles
+}
+
+// buildOPML renders an OPML subscription list with one outline per series.
+// Series with invalid S3 paths are left out.
+func buildOPML(series []Series, titles []string, settings Settings, now time.Time) (string, error) {
+	doc := opmlDocument{
+		Version: "2.0",
+		Head:    opmlHead{Title: "Podcast feeds", DateCreated: now.Format(time.RFC1123Z)},
+	}
+
+	for i, s := range series {
+		url, err := generateS3URL(s.S3Path, settings)
+		if err != nil {
+			slog.Warn("leaving series out of OPML", "guid", s.GUID, "err", err)
+			continue
+		}
+		doc.Body.Outlines = append(doc.Body.Outlines, opmlOutline{Type: "rss", Text: titles[i], Title: titles[i], XMLURL: url})
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal OPML: %w", err)
+	}
+	return xml.Header + string(data) + "\n", nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestBuildOPML(t *testing.T) {
+	settings := Settings{PublicBaseURL: "https://feeds.example.com"}
+	series := []Series{
+		{GUID: "one", S3Path: "s3://bucket/podcasts/one.rss"},
+		{GUID: "bad", S3Path: "not-an-s3-path"},
+		{GUID: "two", S3Path: "s3://bucket/podcasts/two.rss"},
+	}
+
+	out, err := buildOPML(series, []string{"First & best", "Bad", "Second"}, settings, fixedNow)
+	if err != nil {
+		t.Fatalf("buildOPML() error = %v", err)
+	}
+	var doc opmlDocument
+	if err := xml.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("OPML does not parse: %v", err)
+	}
+	if doc.Version != "2.0" || doc.Head.Title == "" || doc.Head.DateCreated != "Sat, 01 Jun 2024 12:00:00 +0000" {
+		t.Errorf("head = version %q, %+v", doc.Version, doc.Head)
+	}
+	want := []opmlOutline{
+		{Type: "rss", Text: "First & best", Title: "First & best", XMLURL: "https://feeds.example.com/podcasts/one.rss"},
+		{Type: "rss", Text: "Second", Title: "Second", XMLURL: "https://feeds.example.com/podcasts/two.rss"},
+	}
+	if !slices.Equal(doc.Body.Outlines, want) {
+		t.Errorf("outlines = %+v, want %+v", doc.Body.Outlines, want)
+	}
+}
+
+func TestSeriesTitlesFallback(t *testing.T) {
+	settings := apiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/one.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"data": {"guid": "one", "title": "First"}}`))
+	})
+	series := []Series{
+		{GUID: "one", S3Path: "s3://bucket/podcasts/one.rss"},
+		{GUID: "gone", S3Path: "s3://bucket/podcasts/gone.rss"},
+	}
+
+	titles := seriesTitles(series, settings)
+	if want := []string{"First", "gone.rss"}; !slices.Equal(titles, want) {
+		t.Errorf("seriesTitles() = %q, want %q", titles, want)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"github.com/charmbracelet/lipgloss"
+)
+
+// pager is a dismissible, scrollable full-screen text view used for batch
+// summaries and feed diffs.
+type pager struct {
+	title  string
+	lines  []string
+	footer string
+	offset int
+}
+
+func newPager(title string, lines []string, footer string) *pager {
+	return &pager{title: title, lines: lines, footer: footer}
+}
+
+func (p *pager) scroll(delta, pageSize int) {
+	p.offset = min(max(p.offset+delta, 0), max(len(p.lines)-pageSize, 0))
+}
+
+func (p *pager) view(pageSize int) string {
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("205"))
+	statusStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+
+	s := headerStyle.Render(p.title) + "\n\n"
+
+	end := min(p.offset+pageSize, len(p.lines))
+	for _, line := range p.lines[p.offset:end] {
+		s += line + "\n"
+	}
+
+	footer := "j/k: scroll • esc/enter: dismiss"
+	if p.footer != "" {
+		footer = p.footer + " • " + footer
+	}
+	s += "\n" + statusStyle.Render(footer)
+	return s
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// episodePicker is a full-screen view listing a series' episodes, letting
+// the user filter them by title or tag and choose which to publish.
+type episodePicker struct {
+	series Series
+	data   *SeriesData
+	filter string
+	// typing is set while keystrokes edit the filter
+	typing bool
+	// cursor and offset index into visible()
+	cursor int
+	offset int
+	// selected holds indexes into data.Episodes
+	selected map[int]bool
+}
+
+func newEpisodePicker(series Series, data *SeriesData) *episodePicker {
+	return &episodePicker{series: series, data: data, selected: make(map[int]bool)}
+}
+
+// matchesFilter reports whether the episode title or one of its tags
+// contains filter, ignoring case.
+func matchesFilter(episode Episode, filter string) bool {
+	filter = strings.ToLower(strings.TrimSpace(filter))
+	if filter == "" || strings.Contains(strings.ToLower(episode.Title), filter) {
+		return true
+	}
+	for _, tag := range episode.Tags {
+		if strings.Contains(strings.ToLower(tag), filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// visible returns the indexes of the episodes matching the filter.
+func (p *episodePicker) visible() []int {
+	var indexes []int
+	for i, episode := range p.data.Episodes {
+		if matchesFilter(episode, p.filter) {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// chosen returns the selected episodes in feed order, or every visible
+// episode when nothing is selected.
+func (p *episodePicker) chosen() []Episode {
+	var episodes []Episode
+	for _, i := range p.visible() {
+		if len(p.selected) == 0 || p.selected[i] {
+			episodes = append(episodes, p.data.Episodes[i])
+		}
+	}
+	return episodes
+}
+
+func (p *episodePicker) setFilter(filter string) {
+	p.filter = filter
+	p.cursor, p.offset = 0, 0
+}
+
+func (p *episodePicker) move(delta, pageSize int) {
+	visible := p.visible()
+	p.cursor = min(max(p.cursor+delta, 0), max(len(visible)-1, 0))
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	} else if p.cursor >= p.offset+pageSize {
+		p.offset = p.cursor - pageSize + 1
+	}
+}
+
+func (p *episodePicker) toggle() {
+	visible := p.visible()
+	if p.cursor >= len(visible) {
+		return
+	}
+	i := visible[p.cursor]
+	if p.selected[i] {
+		delete(p.selected, i)
+	} else {
+		p.selected[i] = true
+	}
+}
+
+// toggleAll selects every visible episode, or clears them if all of them
+// are already selected.
+func (p *episodePicker) toggleAll() {
+	visible := p.visible()
+	all := true
+	for _, i := range visible {
+		all = all && p.selected[i]
+	}
+	for _, i := range visible {
+		if all {
+			delete(p.selected, i)
+		} else {
+			p.selected[i] = true
+		}
+	}
+}
+
+func (p *episodePicker) view(pageSize int) string {
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("205"))
+	selectedStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("170"))
+	statusStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+
+	s := headerStyle.Render("Episodes of "+p.data.Title) + "\n\n"
+
+	filter := p.filter
+	if p.typing {
+		filter += "_"
+	}
+	s += fmt.Sprintf("Filter: %s\n\n", filter)
+
+	visible := p.visible()
+	// The filter line takes two rows of the page
+	pageSize = max(pageSize-2, 1)
+	end := min(p.offset+pageSize, len(visible))
+	for row := p.offset; row < end; row++ {
+		episode := p.data.Episodes[visible[row]]
+		cursor, mark := " ", "[ ]"
+		if row == p.cursor {
+			cursor = ">"
+		}
+		if p.selected[visible[row]] {
+			mark = "[x]"
+		}
+		line := fmt.Sprintf("%s %s %s", cursor, mark, episode.Title)
+		if episode.PublicationDate != "" {
+			line += statusStyle.Render(" (" + episode.PublicationDate + ")")
+		}
+		if row == p.cursor {
+			line = selectedStyle.Render(line)
+		}
+		s += line + "\n"
+	}
+	if len(visible) == 0 {
+		s += statusStyle.Render("no episodes match the filter") + "\n"
+	}
+
+	summary := fmt.Sprintf("%d of %d shown, %d selected", len(visible), len(p.data.Episodes), len(p.selected))
+	help := "/: filter • space: select • a: select all shown • enter: generate • esc: back"
+	if p.typing {
+		help = "type to filter • enter: done • esc: clear"
+	}
+	s += "\n" + statusStyle.Render(summary+" • "+help)
+	return s
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"strings"
+)
+
+// podcastNamespace is the UUID namespace the Podcast Namespace specifies for
+// podcast:guid values.
+var podcastNamespace = [16]byte{
+	0xea, 0xd4, 0xc2, 0x36, 0xbf, 0x58, 0x58, 0xc6,
+	0xa2, 0xc6, 0xa6, 0xb2, 0x8d, 0x12, 0x8c, 0xb6,
+}
+
+// podcastGUID derives the podcast:guid for a feed: a UUIDv5 over the feed
+// URL with the scheme and any trailing slashes removed, so the same show
+// gets the same identifier no matter who computes it.
+func podcastGUID(feedURL string) string {
+	name := feedURL
+	if i := strings.Index(name, "://"); i >= 0 {
+		name = name[i+len("://"):]
+	}
+	name = strings.TrimRight(name, "/")
+
+	h := sha1.New()
+	h.Write(podcastNamespace[:])
+	h.Write([]byte(name))
+	var u [16]byte
+	copy(u[:], h.Sum(nil))
+	u[6] = u[6]&0x0f | 0x50 // version 5
+	u[8] = u[8]&0x3f | 0x80 // RFC 4122 variant
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPodcastGUID(t *testing.T) {
+	// The example from the Podcast Namespace specification
+	const want = "9b024349-ccf0-5f69-a609-6b82873eab3c"
+	for _, feedURL := range []string{"https://podnews.net/rss", "http://podnews.net/rss", "podnews.net/rss/"} {
+		if got := podcastGUID(feedURL); got != want {
+			t.Errorf("podcastGUID(%q) = %s, want %s", feedURL, got, want)
+		}
+	}
+	if podcastGUID("https://podnews.net/other") == want {
+		t.Error("different feed URLs got the same podcast:guid")
+	}
+}
+
+func TestGenerateRSSFeedPodcastGUID(t *testing.T) {
+	seriesData := &SeriesData{GUID: "series-1", Title: "Show"}
+	series := Series{S3Path: "s3://bucket/podcasts/show.rss"}
+	settings := Settings{PublicBaseURL: "https://feeds.example.com"}
+
+	rssXML, _, err := generateRSSFeed(seriesData, series, false, settings, fixedNow)
+	if err != nil {
+		t.Fatalf("generateRSSFeed() error = %v", err)
+	}
+	want := "<podcast:guid>" + podcastGUID("https://feeds.example.com/podcasts/show.rss") + "</podcast:guid>"
+	if !strings.Contains(rssXML, want) {
+		t.Errorf("feed lacks %s:\n%s", want, rssXML)
+	}
+}
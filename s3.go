@@ -1,53 +1,489 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
 )
 
+// s3API is the subset of the S3 client sumppi uses, so tests can
+// substitute a fake.
+type s3API interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	GetObjectAcl(ctx context.Context, params *s3.GetObjectAclInput, optFns ...func(*s3.Options)) (*s3.GetObjectAclOutput, error)
+	GetBucketVersioning(ctx context.Context, params *s3.GetBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error)
+	ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error)
+	CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+}
+
 type S3Client struct {
-	client *s3.Client
+	client      s3API
+	compress    bool
+	sse         string
+	kmsKeyID    string
+	maxAttempts int
 }
 
-func NewS3Client(ctx context.Context) (*S3Client, error) {
-	cfg, err := config.LoadDefaultConfig(ctx)
+// loadAWSConfig resolves the AWS configuration; tests replace it.
+var loadAWSConfig = config.LoadDefaultConfig
+
+func NewS3Client(ctx context.Context, settings Settings) (*S3Client, error) {
+	var opts []func(*config.LoadOptions) error
+	if settings.AWSProfile != "" {
+		slog.Debug("using AWS profile", "profile", settings.AWSProfile)
+		opts = append(opts, config.WithSharedConfigProfile(settings.AWSProfile))
+	} else {
+		slog.Debug("using the default AWS credential chain")
+	}
+
+	cfg, err := loadAWSConfig(ctx, opts...)
+	if err != nil && settings.AWSProfile != "" {
+		return nil, fmt.Errorf("failed to load AWS config for profile %q (check ~/.aws/config and ~/.aws/credentials): %w", settings.AWSProfile, err)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
 
+	if settings.AssumeRoleARN != "" {
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), settings.AssumeRoleARN, func(o *stscreds.AssumeRoleOptions) {
+			if settings.ExternalID != "" {
+				o.ExternalID = aws.String(settings.ExternalID)
+			}
+		})
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+
 	return &S3Client{
-		client: s3.NewFromConfig(cfg),
+		client:      s3.NewFromConfig(cfg),
+		compress:    settings.CompressUpload,
+		sse:         settings.ServerSideEncryption,
+		kmsKeyID:    settings.KMSKeyID,
+		maxAttempts: settings.uploadMaxAttempts(),
 	}, nil
 }
 
-func (s *S3Client) UploadRSSContent(ctx context.Context, rssContent, s3Path string) error {
+// UploadFeed uploads a rendered feed with the given content type.
+func (s *S3Client) UploadFeed(ctx context.Context, content, s3Path, contentType string) error {
+	return s.uploadContent(ctx, content, s3Path, contentType)
+}
+
+// contentHashKey is the object metadata key holding the SHA-256 of the
+// uncompressed content and upload parameters, used to detect unchanged feeds.
+const contentHashKey = "content-sha256"
+
+// contentHash hashes the content together with the parameters it is
+// uploaded with, so switching compression, encryption or content type
+// re-uploads an otherwise unchanged feed.
+func (s *S3Client) contentHash(content, contentType string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "compress=%t\nsse=%s\nkms=%s\ntype=%s\n\n", s.compress, s.sse, s.kmsKeyID, contentType)
+	io.WriteString(h, content)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// UploadFeedIfChanged uploads the feed unless the object at s3Path already
+// holds identical content. It reports whether an upload happened.
+func (s *S3Client) UploadFeedIfChanged(ctx context.Context, content, s3Path, contentType string) (bool, error) {
+	bucket, key, err := parseS3Path(s3Path)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse S3 path: %w", err)
+	}
+
+	// Any lookup failure, including a missing object, falls through to upload
+	head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err == nil && head.Metadata[contentHashKey] == s.contentHash(content, contentType) {
+		slog.Debug("feed unchanged, skipping upload", "bucket", bucket, "key", key)
+		return false, nil
+	}
+
+	if err := s.UploadFeed(ctx, content, s3Path, contentType); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UploadAssetIfChanged is UploadFeedIfChanged for binary assets such as
+// cover art, which directories fetch directly: they are never gzip-encoded,
+// and SSE-KMS, which anonymous readers cannot decrypt, becomes SSE-S3.
+func (s *S3Client) UploadAssetIfChanged(ctx context.Context, content, s3Path, contentType string) (bool, error) {
+	asset := *s
+	asset.compress = false
+	asset.kmsKeyID = ""
+	if asset.sse != "" {
+		asset.sse = string(types.ServerSideEncryptionAes256)
+	}
+	return asset.UploadFeedIfChanged(ctx, content, s3Path, contentType)
+}
+
+// uploadContent stores content at s3Path as a public object, applying the
+// configured compression and encryption.
+func (s *S3Client) uploadContent(ctx context.Context, content, s3Path, contentType string) error {
 	// Parse S3 path (s3://bucket/key)
 	bucket, key, err := parseS3Path(s3Path)
 	if err != nil {
 		return fmt.Errorf("failed to parse S3 path: %w", err)
 	}
 
-	// Upload to S3 directly from memory
-	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+	input := &s3.PutObjectInput{
 		Bucket:      aws.String(bucket),
 		Key:         aws.String(key),
-		Body:        strings.NewReader(rssContent),
-		ContentType: aws.String("application/rss+xml"),
+		ContentType: aws.String(contentType),
 		ACL:         types.ObjectCannedACLPublicRead,
-	})
+		Metadata:    map[string]string{contentHashKey: s.contentHash(content, contentType)},
+	}
+
+	if s.sse != "" {
+		input.ServerSideEncryption = types.ServerSideEncryption(s.sse)
+	}
+	if s.kmsKeyID != "" {
+		input.SSEKMSKeyId = aws.String(s.kmsKeyID)
+	}
+
+	body := []byte(content)
+	if s.compress {
+		body, err = gzipContent(content)
+		if err != nil {
+			return fmt.Errorf("failed to compress content: %w", err)
+		}
+		input.ContentEncoding = aws.String("gzip")
+	}
+
+	// Upload to S3 directly from memory, retrying when S3 asks us to slow down
+	attempts := 0
+	for {
+		attempts++
+		input.Body = bytes.NewReader(body)
+		slog.Debug("uploading to S3", "bucket", bucket, "key", key, "gzip", s.compress, "attempt", attempts)
+		_, err = s.client.PutObject(ctx, input)
+		if !isThrottled(err) || attempts >= s.maxAttempts {
+			break
+		}
+
+		delay := uploadRetryDelay << (attempts - 1)
+		slog.Warn("S3 throttled upload, retrying", "bucket", bucket, "key", key, "attempt", attempts, "delay", delay)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to upload to S3: %w", ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+	if isThrottled(err) {
+		return fmt.Errorf("failed to upload to S3 after %d attempts: %w", attempts, err)
+	}
+	if isAccessDenied(err) {
+		return fmt.Errorf("failed to upload to S3 (the credentials need s3:PutObject and s3:PutObjectAcl on %s/%s, plus sts:AssumeRole if a role is configured): %w", bucket, key, err)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to upload to S3: %w", err)
 	}
+	slog.Debug("uploaded to S3", "bucket", bucket, "key", key)
 
 	return nil
 }
 
+// ErrNotVersioned is returned by RestorePreviousVersion for buckets without
+// versioning, where no earlier copy of an object is kept.
+var ErrNotVersioned = errors.New("bucket versioning is not enabled")
+
+// RestorePreviousVersion makes the version before the current one of the
+// object at s3Path current again by copying it over the key, so the undo can
+// itself be undone. It returns the restored version ID.
+func (s *S3Client) RestorePreviousVersion(ctx context.Context, s3Path string) (string, error) {
+	bucket, key, err := parseS3Path(s3Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse S3 path: %w", err)
+	}
+
+	versioning, err := s.client.GetBucketVersioning(ctx, &s3.GetBucketVersioningInput{Bucket: aws.String(bucket)})
+	if err != nil {
+		return "", fmt.Errorf("failed to get versioning status of %s: %w", bucket, err)
+	}
+	if versioning.Status != types.BucketVersioningStatusEnabled {
+		return "", fmt.Errorf("%s: %w", bucket, ErrNotVersioned)
+	}
+
+	var versions []types.ObjectVersion
+	paginator := s3.NewListObjectVersionsPaginator(s.client, &s3.ListObjectVersionsInput{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(key),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to list versions of %s: %w", s3Path, err)
+		}
+		// The prefix also matches longer keys
+		for _, v := range page.Versions {
+			if aws.ToString(v.Key) == key {
+				versions = append(versions, v)
+			}
+		}
+	}
+
+	// S3 lists versions newest first, but sort explicitly rather than rely
+	// on it; the current version is the one flagged IsLatest.
+	sort.SliceStable(versions, func(i, j int) bool {
+		return aws.ToTime(versions[i].LastModified).After(aws.ToTime(versions[j].LastModified))
+	})
+	if len(versions) < 2 || !aws.ToBool(versions[0].IsLatest) {
+		return "", fmt.Errorf("%s has no previous version to restore", s3Path)
+	}
+	previous := aws.ToString(versions[1].VersionId)
+
+	input := &s3.CopyObjectInput{
+		Bucket:     aws.String(bucket),
+		Key:        aws.String(key),
+		CopySource: aws.String(copySource(bucket, key, previous)),
+		ACL:        types.ObjectCannedACLPublicRead,
+	}
+	if s.sse != "" {
+		input.ServerSideEncryption = types.ServerSideEncryption(s.sse)
+	}
+	if s.kmsKeyID != "" {
+		input.SSEKMSKeyId = aws.String(s.kmsKeyID)
+	}
+
+	slog.Debug("restoring previous version", "bucket", bucket, "key", key, "version", previous)
+	if _, err := s.client.CopyObject(ctx, input); err != nil {
+		return "", fmt.Errorf("failed to restore version %s of %s: %w", previous, s3Path, err)
+	}
+	return previous, nil
+}
+
+// copySource builds the URL-encoded CopySource of one object version.
+func copySource(bucket, key, versionID string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return bucket + "/" + strings.Join(segments, "/") + "?versionId=" + url.QueryEscape(versionID)
+}
+
+// ErrFeedNotFound is returned by GetRSS when no object exists at the path.
+var ErrFeedNotFound = errors.New("feed not found")
+
+// maxFeedSize caps how much of an S3 object GetRSS will read.
+const maxFeedSize = 10 << 20
+
+// GetRSS downloads the feed currently stored at s3Path, transparently
+// decompressing gzip-encoded objects. Objects larger than maxFeedSize are
+// rejected rather than read into memory.
+func (s *S3Client) GetRSS(ctx context.Context, s3Path string) (string, error) {
+	bucket, key, err := parseS3Path(s3Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse S3 path: %w", err)
+	}
+
+	slog.Debug("downloading from S3", "bucket", bucket, "key", key)
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return "", fmt.Errorf("%s: %w", s3Path, ErrFeedNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to download from S3: %w", err)
+	}
+	defer out.Body.Close()
+
+	if out.ContentLength != nil && *out.ContentLength > maxFeedSize {
+		return "", fmt.Errorf("S3 object is %d bytes, larger than the %d byte limit", *out.ContentLength, maxFeedSize)
+	}
+
+	var body io.Reader = out.Body
+	if aws.ToString(out.ContentEncoding) == "gzip" {
+		zr, err := gzip.NewReader(out.Body)
+		if err != nil {
+			return "", fmt.Errorf("failed to decompress S3 object: %w", err)
+		}
+		defer zr.Close()
+		body = zr
+	}
+
+	// Read one byte past the cap to detect oversized bodies
+	var sb strings.Builder
+	n, err := io.Copy(&sb, io.LimitReader(body, maxFeedSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read S3 object: %w", err)
+	}
+	if n > maxFeedSize {
+		return "", fmt.Errorf("S3 object exceeds the %d byte limit", maxFeedSize)
+	}
+
+	return sb.String(), nil
+}
+
+// writeCheckKey is the name of the zero-byte object CheckWritable writes
+// next to the feed and deletes again.
+const writeCheckKey = ".sumppi-write-check"
+
+// CheckWritable confirms the bucket of s3Path exists and that a public
+// object can be written next to the feed, using the same settings as a real
+// upload.
+func (s *S3Client) CheckWritable(ctx context.Context, s3Path string) error {
+	bucket, key, err := parseS3Path(s3Path)
+	if err != nil {
+		return fmt.Errorf("failed to parse S3 path: %w", err)
+	}
+
+	_, err = s.client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucket)})
+	var notFound *types.NotFound
+	switch {
+	case errors.As(err, &notFound) || httpStatus(err) == http.StatusNotFound:
+		return fmt.Errorf("bucket %s does not exist", bucket)
+	case isAccessDenied(err) || httpStatus(err) == http.StatusForbidden:
+		return fmt.Errorf("access to bucket %s denied (the credentials need s3:ListBucket on it): %w", bucket, err)
+	case err != nil:
+		return fmt.Errorf("failed to access bucket %s: %w", bucket, err)
+	}
+
+	probeKey := path.Join(path.Dir(key), writeCheckKey)
+	probe := "s3://" + bucket + "/" + probeKey
+	if err := s.uploadContent(ctx, "", probe, "text/plain"); err != nil {
+		return err
+	}
+
+	if _, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(probeKey),
+	}); err != nil {
+		return fmt.Errorf("writable, but failed to delete %s (grant s3:DeleteObject or remove it by hand): %w", probe, err)
+	}
+	return nil
+}
+
+// allUsersURI identifies the anonymous grantee in S3 ACLs.
+const allUsersURI = "http://acs.amazonaws.com/groups/global/AllUsers"
+
+// IsPublic reports whether the object at s3Path grants anyone read access,
+// as every upload requests with the public-read ACL.
+func (s *S3Client) IsPublic(ctx context.Context, s3Path string) (bool, error) {
+	bucket, key, err := parseS3Path(s3Path)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse S3 path: %w", err)
+	}
+
+	out, err := s.client.GetObjectAcl(ctx, &s3.GetObjectAclInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	var noSuchKey *types.NoSuchKey
+	switch {
+	case errors.As(err, &noSuchKey) || httpStatus(err) == http.StatusNotFound:
+		return false, fmt.Errorf("object does not exist")
+	case isAccessDenied(err):
+		return false, fmt.Errorf("failed to read ACL (the credentials need s3:GetObjectAcl on %s/%s): %w", bucket, key, err)
+	case err != nil:
+		return false, fmt.Errorf("failed to read ACL: %w", err)
+	}
+	return grantsPublicRead(out.Grants), nil
+}
+
+// grantsPublicRead reports whether grants give anonymous users read access.
+func grantsPublicRead(grants []types.Grant) bool {
+	for _, grant := range grants {
+		if grant.Grantee == nil || grant.Grantee.Type != types.TypeGroup || aws.ToString(grant.Grantee.URI) != allUsersURI {
+			continue
+		}
+		if grant.Permission == types.PermissionRead || grant.Permission == types.PermissionFullControl {
+			return true
+		}
+	}
+	return false
+}
+
+// httpStatus returns the HTTP status code of a failed S3 request, or 0.
+func httpStatus(err error) int {
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.HTTPStatusCode()
+	}
+	return 0
+}
+
+func isAccessDenied(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "AccessDenied"
+}
+
+// uploadRetryDelay is the wait before the first retry of a throttled upload;
+// it doubles with each further attempt. Tests shorten it.
+var uploadRetryDelay = 500 * time.Millisecond
+
+// isThrottled reports whether S3 rejected a request because of load, which
+// is worth retrying after a pause.
+func isThrottled(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "SlowDown", "ServiceUnavailable", "RequestLimitExceeded":
+			return true
+		}
+	}
+	return httpStatus(err) == http.StatusServiceUnavailable
+}
+
+func gzipContent(content string) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(content)); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// cdnURL maps the object through the longest matching "bucket" or
+// "bucket/prefix" entry. The matched prefix is replaced by the base URL.
+func cdnURL(bucket, key string, mappings map[string]string) (string, bool) {
+	fullPath := bucket + "/" + key
+	bestMatch := ""
+	for prefix := range mappings {
+		trimmed := strings.Trim(prefix, "/")
+		if (fullPath == trimmed || strings.HasPrefix(fullPath, trimmed+"/")) && len(trimmed) > len(strings.Trim(bestMatch, "/")) {
+			bestMatch = prefix
+		}
+	}
+	if bestMatch == "" {
+		return "", false
+	}
+
+	rest := strings.TrimPrefix(strings.TrimPrefix(fullPath, strings.Trim(bestMatch, "/")), "/")
+	return strings.TrimSuffix(mappings[bestMatch], "/") + "/" + rest, true
+}
+
 func parseS3Path(s3Path string) (bucket, key string, err error) {
 	if !strings.HasPrefix(s3Path, "s3://") {
 		return "", "", fmt.Errorf("invalid S3 path: must start with s3://")
@@ -55,18 +491,62 @@ func parseS3Path(s3Path string) (bucket, key string, err error) {
 
 	path := strings.TrimPrefix(s3Path, "s3://")
 	parts := strings.SplitN(path, "/", 2)
-	if len(parts) != 2 {
+	if len(parts) != 2 || parts[0] == "" {
 		return "", "", fmt.Errorf("invalid S3 path: must be in format s3://bucket/key")
 	}
 
-	return parts[0], parts[1], nil
+	key, err = normalizeS3Key(parts[1])
+	if err != nil {
+		return "", "", fmt.Errorf("invalid S3 path: %w", err)
+	}
+
+	return parts[0], key, nil
 }
 
-func generateS3URL(s3Path string) (string, error) {
+// normalizeS3Key trims leading slashes and collapses repeated ones. Empty
+// keys, trailing slashes and "." or ".." segments are rejected.
+func normalizeS3Key(key string) (string, error) {
+	if strings.HasSuffix(key, "/") {
+		return "", fmt.Errorf("key %q must not end with a slash", key)
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(key, "/") {
+		switch segment {
+		case "":
+			continue
+		case ".", "..":
+			return "", fmt.Errorf("key %q must not contain %q segments", key, segment)
+		}
+		segments = append(segments, segment)
+	}
+
+	if len(segments) == 0 {
+		return "", fmt.Errorf("key must not be empty")
+	}
+	return strings.Join(segments, "/"), nil
+}
+
+// generateS3URL returns the public URL of the object. A matching CDN
+// mapping wins, then the configured public base URL; otherwise the URL uses
+// the bucket's regional endpoint, which avoids the redirect the global
+// endpoint returns outside us-east-1.
+func generateS3URL(s3Path string, settings Settings) (string, error) {
 	bucket, key, err := parseS3Path(s3Path)
 	if err != nil {
 		return "", err
 	}
 
+	if url, ok := cdnURL(bucket, key, settings.CDNURLs); ok {
+		return url, nil
+	}
+
+	if settings.PublicBaseURL != "" {
+		return strings.TrimSuffix(settings.PublicBaseURL, "/") + "/" + key, nil
+	}
+
+	if region := settings.s3Region(); region != "" {
+		return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, region, key), nil
+	}
 	return fmt.Sprintf("https://%s.s3.amazonaws.com/%s", bucket, key), nil
 }
@@ -0,0 +1,646 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// fakeObject is one object stored in fakeS3.
+type fakeObject struct {
+	body     []byte
+	metadata map[string]string
+	encoding string
+	grants   []types.Grant
+}
+
+// fakeS3 is an in-memory s3API. Objects are keyed by "bucket/key".
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]fakeObject
+	puts    []*s3.PutObjectInput
+	// putErrs are returned by successive PutObject calls before they succeed
+	putErrs []error
+
+	headBucketErr error
+	aclErr        error
+	deleted       []string
+
+	versioning types.BucketVersioningStatus
+	versions   []types.ObjectVersion
+	copies     []*s3.CopyObjectInput
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: make(map[string]fakeObject)}
+}
+
+func (f *fakeS3) client() *S3Client {
+	return &S3Client{client: f, maxAttempts: defaultUploadMaxAttempts}
+}
+
+func (f *fakeS3) PutObject(_ context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.puts = append(f.puts, in)
+	if len(f.putErrs) > 0 {
+		err := f.putErrs[0]
+		f.putErrs = f.putErrs[1:]
+		return nil, err
+	}
+	body, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+	obj := fakeObject{body: body, metadata: in.Metadata, encoding: aws.ToString(in.ContentEncoding)}
+	if in.ACL == types.ObjectCannedACLPublicRead {
+		obj.grants = []types.Grant{publicReadGrant()}
+	}
+	f.objects[aws.ToString(in.Bucket)+"/"+aws.ToString(in.Key)] = obj
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *fakeS3) object(bucket, key *string) (fakeObject, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	obj, ok := f.objects[aws.ToString(bucket)+"/"+aws.ToString(key)]
+	return obj, ok
+}
+
+func (f *fakeS3) GetObject(_ context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	obj, ok := f.object(in.Bucket, in.Key)
+	if !ok {
+		return nil, &types.NoSuchKey{}
+	}
+	out := &s3.GetObjectOutput{
+		Body:          io.NopCloser(bytes.NewReader(obj.body)),
+		ContentLength: aws.Int64(int64(len(obj.body))),
+	}
+	if obj.encoding != "" {
+		out.ContentEncoding = aws.String(obj.encoding)
+	}
+	return out, nil
+}
+
+func (f *fakeS3) HeadObject(_ context.Context, in *s3.HeadObjectInput, _ ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	obj, ok := f.object(in.Bucket, in.Key)
+	if !ok {
+		return nil, &types.NotFound{}
+	}
+	return &s3.HeadObjectOutput{Metadata: obj.metadata}, nil
+}
+
+func (f *fakeS3) HeadBucket(_ context.Context, _ *s3.HeadBucketInput, _ ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	if f.headBucketErr != nil {
+		return nil, f.headBucketErr
+	}
+	return &s3.HeadBucketOutput{}, nil
+}
+
+func (f *fakeS3) DeleteObject(_ context.Context, in *s3.DeleteObjectInput, _ ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	name := aws.ToString(in.Bucket) + "/" + aws.ToString(in.Key)
+	delete(f.objects, name)
+	f.deleted = append(f.deleted, name)
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (f *fakeS3) GetObjectAcl(_ context.Context, in *s3.GetObjectAclInput, _ ...func(*s3.Options)) (*s3.GetObjectAclOutput, error) {
+	if f.aclErr != nil {
+		return nil, f.aclErr
+	}
+	obj, ok := f.object(in.Bucket, in.Key)
+	if !ok {
+		return nil, &types.NoSuchKey{}
+	}
+	return &s3.GetObjectAclOutput{Grants: obj.grants}, nil
+}
+
+func (f *fakeS3) GetBucketVersioning(_ context.Context, _ *s3.GetBucketVersioningInput, _ ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error) {
+	return &s3.GetBucketVersioningOutput{Status: f.versioning}, nil
+}
+
+func (f *fakeS3) ListObjectVersions(_ context.Context, _ *s3.ListObjectVersionsInput, _ ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	return &s3.ListObjectVersionsOutput{Versions: f.versions}, nil
+}
+
+func (f *fakeS3) CopyObject(_ context.Context, in *s3.CopyObjectInput, _ ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.copies = append(f.copies, in)
+	return &s3.CopyObjectOutput{}, nil
+}
+
+func publicReadGrant() types.Grant {
+	return types.Grant{
+		Grantee:    &types.Grantee{Type: types.TypeGroup, URI: aws.String(allUsersURI)},
+		Permission: types.PermissionRead,
+	}
+}
+
+func TestGetRSS(t *testing.T) {
+	fake := newFakeS3()
+	fake.objects["bucket/feeds/show.rss"] = fakeObject{body: []byte("<rss/>")}
+	client := fake.client()
+
+	got, err := client.GetRSS(context.Background(), "s3://bucket/feeds/show.rss")
+	if err != nil {
+		t.Fatalf("GetRSS() error = %v", err)
+	}
+	if got != "<rss/>" {
+		t.Errorf("GetRSS() = %q, want %q", got, "<rss/>")
+	}
+
+	_, err = client.GetRSS(context.Background(), "s3://bucket/feeds/missing.rss")
+	if !errors.Is(err, ErrFeedNotFound) {
+		t.Errorf("GetRSS() of a missing object error = %v, want ErrFeedNotFound", err)
+	}
+}
+
+func TestGetRSSGzip(t *testing.T) {
+	fake := newFakeS3()
+	compressed, err := gzipContent("<rss>compressed</rss>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.objects["bucket/show.rss"] = fakeObject{body: compressed, encoding: "gzip"}
+
+	got, err := fake.client().GetRSS(context.Background(), "s3://bucket/show.rss")
+	if err != nil {
+		t.Fatalf("GetRSS() error = %v", err)
+	}
+	if got != "<rss>compressed</rss>" {
+		t.Errorf("GetRSS() = %q, want the decompressed feed", got)
+	}
+}
+
+func TestGetRSSSizeCap(t *testing.T) {
+	fake := newFakeS3()
+	fake.objects["bucket/huge.rss"] = fakeObject{body: []byte(strings.Repeat("x", maxFeedSize+1))}
+
+	if _, err := fake.client().GetRSS(context.Background(), "s3://bucket/huge.rss"); err == nil {
+		t.Error("GetRSS() of an oversized object succeeded, want an error")
+	}
+}
+
+func TestNewS3ClientProfile(t *testing.T) {
+	orig := loadAWSConfig
+	t.Cleanup(func() { loadAWSConfig = orig })
+
+	for _, profile := range []string{"", "publishing"} {
+		var opts config.LoadOptions
+		loadAWSConfig = func(_ context.Context, optFns ...func(*config.LoadOptions) error) (aws.Config, error) {
+			for _, fn := range optFns {
+				if err := fn(&opts); err != nil {
+					return aws.Config{}, err
+				}
+			}
+			return aws.Config{Region: "eu-north-1"}, nil
+		}
+
+		if _, err := NewS3Client(context.Background(), Settings{AWSProfile: profile}); err != nil {
+			t.Fatalf("NewS3Client(profile %q) error = %v", profile, err)
+		}
+		if opts.SharedConfigProfile != profile {
+			t.Errorf("profile passed to the loader = %q, want %q", opts.SharedConfigProfile, profile)
+		}
+	}
+}
+
+func TestNewS3ClientProfileError(t *testing.T) {
+	orig := loadAWSConfig
+	t.Cleanup(func() { loadAWSConfig = orig })
+	loadAWSConfig = func(context.Context, ...func(*config.LoadOptions) error) (aws.Config, error) {
+		return aws.Config{}, errors.New("failed to get shared config profile, missing")
+	}
+
+	_, err := NewS3Client(context.Background(), Settings{AWSProfile: "missing"})
+	if err == nil || !strings.Contains(err.Error(), `profile "missing"`) {
+		t.Errorf("NewS3Client() error = %v, want it to name the profile", err)
+	}
+}
+
+func TestUploadFeedIfChanged(t *testing.T) {
+	fake := newFakeS3()
+	client := fake.client()
+	ctx := context.Background()
+	upload := func(content string) bool {
+		t.Helper()
+		changed, err := client.UploadFeedIfChanged(ctx, content, "s3://bucket/show.rss", "application/rss+xml")
+		if err != nil {
+			t.Fatalf("UploadFeedIfChanged() error = %v", err)
+		}
+		return changed
+	}
+
+	if !upload("<rss>1</rss>") {
+		t.Error("first upload was skipped")
+	}
+	if upload("<rss>1</rss>") {
+		t.Error("identical content was uploaded again")
+	}
+	if !upload("<rss>2</rss>") {
+		t.Error("changed content was skipped")
+	}
+
+	client.compress = true
+	if !upload("<rss>2</rss>") {
+		t.Error("switching on compression did not re-upload the feed")
+	}
+	if obj := fake.objects["bucket/show.rss"]; obj.encoding != "gzip" {
+		t.Errorf("stored encoding = %q, want gzip", obj.encoding)
+	}
+
+	client.sse, client.kmsKeyID = "aws:kms", "key-1"
+	if !upload("<rss>2</rss>") {
+		t.Error("changing encryption did not re-upload the feed")
+	}
+	client.kmsKeyID = "key-2"
+	if !upload("<rss>2</rss>") {
+		t.Error("changing the KMS key did not re-upload the feed")
+	}
+	if upload("<rss>2</rss>") {
+		t.Error("identical content and settings were uploaded again")
+	}
+	if len(fake.puts) != 5 {
+		t.Errorf("PutObject calls = %d, want 5", len(fake.puts))
+	}
+}
+
+func TestContentHashCoversUploadSettings(t *testing.T) {
+	const content = "<rss/>"
+	base := (&S3Client{}).contentHash(content, "application/rss+xml")
+	if again := (&S3Client{}).contentHash(content, "application/rss+xml"); again != base {
+		t.Fatalf("contentHash() = %s, then %s for the same input", base, again)
+	}
+
+	variants := map[string]string{
+		"compression":  (&S3Client{compress: true}).contentHash(content, "application/rss+xml"),
+		"encryption":   (&S3Client{sse: "AES256"}).contentHash(content, "application/rss+xml"),
+		"KMS key":      (&S3Client{sse: "aws:kms", kmsKeyID: "key-1"}).contentHash(content, "application/rss+xml"),
+		"content type": (&S3Client{}).contentHash(content, "application/feed+json"),
+	}
+	for name, hash := range variants {
+		if hash == base {
+			t.Errorf("changing the %s left the content hash unchanged", name)
+		}
+	}
+}
+
+func TestUploadFeedCompressed(t *testing.T) {
+	fake := newFakeS3()
+	client := fake.client()
+	client.compress = true
+
+	if err := client.UploadFeed(context.Background(), "<rss>feed</rss>", "s3://bucket/show.rss", "application/rss+xml"); err != nil {
+		t.Fatalf("UploadFeed() error = %v", err)
+	}
+	put := fake.puts[0]
+	if got := aws.ToString(put.ContentEncoding); got != "gzip" {
+		t.Errorf("ContentEncoding = %q, want gzip", got)
+	}
+	if got := aws.ToString(put.ContentType); got != "application/rss+xml" {
+		t.Errorf("ContentType = %q, want application/rss+xml", got)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(fake.objects["bucket/show.rss"].body))
+	if err != nil {
+		t.Fatalf("uploaded body is not gzipped: %v", err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "<rss>feed</rss>" {
+		t.Errorf("gunzipped body = %q, want the feed", body)
+	}
+}
+
+func TestUploadFeedEncryption(t *testing.T) {
+	tests := []struct {
+		name     string
+		sse      string
+		kmsKeyID string
+		wantSSE  types.ServerSideEncryption
+		wantKey  string
+	}{
+		{"unset", "", "", "", ""},
+		{"SSE-S3", "AES256", "", types.ServerSideEncryptionAes256, ""},
+		{"SSE-KMS default key", "aws:kms", "", types.ServerSideEncryptionAwsKms, ""},
+		{"SSE-KMS with key", "aws:kms", "arn:aws:kms:eu-north-1:123456789012:key/abc", types.ServerSideEncryptionAwsKms, "arn:aws:kms:eu-north-1:123456789012:key/abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3()
+			client := fake.client()
+			client.sse, client.kmsKeyID = tt.sse, tt.kmsKeyID
+
+			if err := client.UploadFeed(context.Background(), "<rss/>", "s3://bucket/show.rss", "application/rss+xml"); err != nil {
+				t.Fatalf("UploadFeed() error = %v", err)
+			}
+			put := fake.puts[0]
+			if put.ServerSideEncryption != tt.wantSSE {
+				t.Errorf("ServerSideEncryption = %q, want %q", put.ServerSideEncryption, tt.wantSSE)
+			}
+			if tt.wantKey == "" && put.SSEKMSKeyId != nil {
+				t.Errorf("SSEKMSKeyId = %q, want it omitted", aws.ToString(put.SSEKMSKeyId))
+			}
+			if got := aws.ToString(put.SSEKMSKeyId); got != tt.wantKey {
+				t.Errorf("SSEKMSKeyId = %q, want %q", got, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestParseS3Path(t *testing.T) {
+	accepted := []struct {
+		path, bucket, key string
+	}{
+		{"s3://bucket/feed.rss", "bucket", "feed.rss"},
+		{"s3://bucket/podcasts/show/feed.rss", "bucket", "podcasts/show/feed.rss"},
+		{"s3://bucket//podcasts/feed.rss", "bucket", "podcasts/feed.rss"},
+		{"s3://bucket/podcasts//show///feed.rss", "bucket", "podcasts/show/feed.rss"},
+		{"s3://bucket/shows/..feed.rss", "bucket", "shows/..feed.rss"},
+	}
+	for _, tt := range accepted {
+		bucket, key, err := parseS3Path(tt.path)
+		if err != nil {
+			t.Errorf("parseS3Path(%q) error = %v", tt.path, err)
+			continue
+		}
+		if bucket != tt.bucket || key != tt.key {
+			t.Errorf("parseS3Path(%q) = %q, %q, want %q, %q", tt.path, bucket, key, tt.bucket, tt.key)
+		}
+	}
+
+	rejected := []string{
+		"bucket/feed.rss",
+		"https://bucket/feed.rss",
+		"s3://bucket",
+		"s3:///feed.rss",
+		"s3://bucket/",
+		"s3://bucket//",
+		"s3://bucket/podcasts/",
+		"s3://bucket/../feed.rss",
+		"s3://bucket/podcasts/../feed.rss",
+		"s3://bucket/./feed.rss",
+	}
+	for _, path := range rejected {
+		if _, _, err := parseS3Path(path); err == nil {
+			t.Errorf("parseS3Path(%q) succeeded, want an error", path)
+		}
+	}
+}
+
+func TestGenerateS3URL(t *testing.T) {
+	tests := []struct {
+		name      string
+		settings  Settings
+		envRegion string
+		want      string
+	}{
+		{"default region", Settings{}, "", "https://bucket.s3.amazonaws.com/podcasts/feed.rss"},
+		{"explicit region", Settings{S3Region: "eu-north-1"}, "us-west-2", "https://bucket.s3.eu-north-1.amazonaws.com/podcasts/feed.rss"},
+		{"region from environment", Settings{}, "us-west-2", "https://bucket.s3.us-west-2.amazonaws.com/podcasts/feed.rss"},
+		{"custom base", Settings{PublicBaseURL: "https://feeds.example.com/", S3Region: "eu-north-1"}, "", "https://feeds.example.com/podcasts/feed.rss"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AWS_REGION", tt.envRegion)
+			t.Setenv("AWS_DEFAULT_REGION", "")
+			got, err := generateS3URL("s3://bucket/podcasts/feed.rss", tt.settings)
+			if err != nil {
+				t.Fatalf("generateS3URL() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("generateS3URL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateS3URLCDN(t *testing.T) {
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	settings := Settings{
+		PublicBaseURL: "https://public.example.com",
+		CDNURLs: map[string]string{
+			"media":          "https://cdn.example.com/",
+			"media/podcasts": "https://podcasts.example.com",
+		},
+	}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"s3://media/other/feed.rss", "https://cdn.example.com/other/feed.rss"},
+		{"s3://media/podcasts/show/feed.rss", "https://podcasts.example.com/show/feed.rss"},
+		{"s3://media/podcastsextra/feed.rss", "https://cdn.example.com/podcastsextra/feed.rss"},
+		{"s3://unmapped/feed.rss", "https://public.example.com/feed.rss"},
+	}
+	for _, tt := range tests {
+		got, err := generateS3URL(tt.path, settings)
+		if err != nil {
+			t.Fatalf("generateS3URL(%q) error = %v", tt.path, err)
+		}
+		if got != tt.want {
+			t.Errorf("generateS3URL(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	delete(settings.CDNURLs, "media")
+	settings.PublicBaseURL = ""
+	if got, _ := generateS3URL("s3://media/other/feed.rss", settings); got != "https://media.s3.amazonaws.com/other/feed.rss" {
+		t.Errorf("generateS3URL() of an unmapped bucket = %q, want the S3 URL", got)
+	}
+}
+
+func TestUploadFeedRetriesThrottling(t *testing.T) {
+	orig := uploadRetryDelay
+	t.Cleanup(func() { uploadRetryDelay = orig })
+	uploadRetryDelay = time.Millisecond
+
+	slowDown := &smithy.GenericAPIError{Code: "SlowDown", Message: "Please reduce your request rate."}
+
+	fake := newFakeS3()
+	fake.putErrs = []error{slowDown, slowDown}
+	if err := fake.client().UploadFeed(context.Background(), "<rss/>", "s3://bucket/show.rss", "application/rss+xml"); err != nil {
+		t.Fatalf("UploadFeed() error = %v, want success on the third attempt", err)
+	}
+	if len(fake.puts) != 3 {
+		t.Errorf("PutObject calls = %d, want 3", len(fake.puts))
+	}
+
+	fake = newFakeS3()
+	fake.putErrs = []error{slowDown, slowDown, slowDown}
+	client := fake.client()
+	client.maxAttempts = 2
+	err := client.UploadFeed(context.Background(), "<rss/>", "s3://bucket/show.rss", "application/rss+xml")
+	if err == nil || !strings.Contains(err.Error(), "after 2 attempts") {
+		t.Errorf("UploadFeed() error = %v, want it to report 2 attempts", err)
+	}
+
+	fake = newFakeS3()
+	fake.putErrs = []error{&smithy.GenericAPIError{Code: "InvalidArgument"}}
+	if err := fake.client().UploadFeed(context.Background(), "<rss/>", "s3://bucket/show.rss", "application/rss+xml"); err == nil {
+		t.Error("UploadFeed() succeeded after a non-throttling error, want it to fail without retrying")
+	}
+	if len(fake.puts) != 1 {
+		t.Errorf("PutObject calls after a non-throttling error = %d, want 1", len(fake.puts))
+	}
+}
+
+func TestCheckWritable(t *testing.T) {
+	accessDenied := &smithy.GenericAPIError{Code: "AccessDenied", Message: "Access Denied"}
+	tests := []struct {
+		name          string
+		headBucketErr error
+		putErrs       []error
+		want          string
+	}{
+		{"writable", nil, nil, ""},
+		{"missing bucket", &types.NotFound{}, nil, "bucket bucket does not exist"},
+		{"bucket denied", accessDenied, nil, "s3:ListBucket"},
+		{"write denied", nil, []error{accessDenied}, "s3:PutObject"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3()
+			fake.headBucketErr = tt.headBucketErr
+			fake.putErrs = tt.putErrs
+
+			err := fake.client().CheckWritable(context.Background(), "s3://bucket/podcasts/show.rss")
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("CheckWritable() error = %v", err)
+				}
+				probe := "bucket/podcasts/" + writeCheckKey
+				if len(fake.deleted) != 1 || fake.deleted[0] != probe {
+					t.Errorf("deleted = %v, want the probe %s", fake.deleted, probe)
+				}
+				if len(fake.objects) != 0 {
+					t.Errorf("objects left behind: %v", fake.objects)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("CheckWritable() error = %v, want one mentioning %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestorePreviousVersion(t *testing.T) {
+	at := func(day int) *time.Time {
+		modified := time.Date(2024, 5, day, 12, 0, 0, 0, time.UTC)
+		return &modified
+	}
+	version := func(key, id string, day int, latest bool) types.ObjectVersion {
+		return types.ObjectVersion{Key: aws.String(key), VersionId: aws.String(id), LastModified: at(day), IsLatest: aws.Bool(latest)}
+	}
+
+	fake := newFakeS3()
+	fake.versioning = types.BucketVersioningStatusEnabled
+	// Listed out of order, with a longer key sharing the prefix
+	fake.versions = []types.ObjectVersion{
+		version("podcasts/show.rss", "oldest", 1, false),
+		version("podcasts/show.rss.bak", "other", 4, true),
+		version("podcasts/show.rss", "current", 3, true),
+		version("podcasts/show.rss", "previous+1", 2, false),
+	}
+
+	restored, err := fake.client().RestorePreviousVersion(context.Background(), "s3://bucket/podcasts/show.rss")
+	if err != nil {
+		t.Fatalf("RestorePreviousVersion() error = %v", err)
+	}
+	if restored != "previous+1" {
+		t.Errorf("restored version = %q, want previous+1", restored)
+	}
+	if len(fake.copies) != 1 {
+		t.Fatalf("CopyObject called %d times, want 1", len(fake.copies))
+	}
+	copied := fake.copies[0]
+	if got := aws.ToString(copied.CopySource); got != "bucket/podcasts/show.rss?versionId=previous%2B1" {
+		t.Errorf("CopySource = %q", got)
+	}
+	if aws.ToString(copied.Key) != "podcasts/show.rss" || copied.ACL != types.ObjectCannedACLPublicRead {
+		t.Errorf("copy = key %q, ACL %q", aws.ToString(copied.Key), copied.ACL)
+	}
+}
+
+func TestRestorePreviousVersionErrors(t *testing.T) {
+	fake := newFakeS3()
+	_, err := fake.client().RestorePreviousVersion(context.Background(), "s3://bucket/show.rss")
+	if !errors.Is(err, ErrNotVersioned) {
+		t.Errorf("unversioned bucket error = %v, want ErrNotVersioned", err)
+	}
+
+	fake.versioning = types.BucketVersioningStatusEnabled
+	fake.versions = []types.ObjectVersion{{Key: aws.String("show.rss"), VersionId: aws.String("only"), IsLatest: aws.Bool(true)}}
+	if _, err := fake.client().RestorePreviousVersion(context.Background(), "s3://bucket/show.rss"); err == nil {
+		t.Error("restoring a single version succeeded, want an error")
+	}
+	if len(fake.copies) != 0 {
+		t.Errorf("CopyObject called %d times, want none", len(fake.copies))
+	}
+}
+
+func TestIsPublic(t *testing.T) {
+	group := func(uri string, permission types.Permission) types.Grant {
+		return types.Grant{Grantee: &types.Grantee{Type: types.TypeGroup, URI: aws.String(uri)}, Permission: permission}
+	}
+	owner := types.Grant{Grantee: &types.Grantee{Type: types.TypeCanonicalUser, ID: aws.String("owner")}, Permission: types.PermissionFullControl}
+	tests := []struct {
+		name   string
+		grants []types.Grant
+		want   bool
+	}{
+		{"public-read", []types.Grant{owner, publicReadGrant()}, true},
+		{"everyone full control", []types.Grant{group(allUsersURI, types.PermissionFullControl)}, true},
+		{"owner only", []types.Grant{owner}, false},
+		{"authenticated users", []types.Grant{owner, group("http://acs.amazonaws.com/groups/global/AuthenticatedUsers", types.PermissionRead)}, false},
+		{"everyone may only read the ACL", []types.Grant{group(allUsersURI, types.PermissionReadAcp)}, false},
+		{"no grantee", []types.Grant{{Permission: types.PermissionRead}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3()
+			fake.objects["bucket/show.rss"] = fakeObject{grants: tt.grants}
+			got, err := fake.client().IsPublic(context.Background(), "s3://bucket/show.rss")
+			if err != nil {
+				t.Fatalf("IsPublic() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsPublic() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsPublicErrors(t *testing.T) {
+	fake := newFakeS3()
+	_, err := fake.client().IsPublic(context.Background(), "s3://bucket/missing.rss")
+	if err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("missing object error = %v, want does not exist", err)
+	}
+
+	fake.aclErr = &smithy.GenericAPIError{Code: "AccessDenied"}
+	_, err = fake.client().IsPublic(context.Background(), "s3://bucket/show.rss")
+	if err == nil || !strings.Contains(err.Error(), "s3:GetObjectAcl") {
+		t.Errorf("access denied error = %v, want the missing permission named", err)
+	}
+}
@@ -1,9 +1,19 @@
 package main
 
 import (
+	"compress/gzip"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -14,11 +24,434 @@ type SeriesConfig struct {
 
 type Settings struct {
 	Timezone string `toml:"timezone"`
+	// PubDateTimezone converts pubDate values into this zone, e.g.
+	// "Europe/Helsinki"; unset keeps the offset the API returned.
+	PubDateTimezone string `toml:"pubdate_timezone"`
+
+	OutputDir      string `toml:"output_dir"`
+	CompressUpload bool   `toml:"compress_upload"`
+	// InvalidDates controls episodes whose publication date cannot be
+	// parsed: "skip" (default) drops them, "omit" keeps them without a pubDate.
+	InvalidDates string `toml:"invalid_dates"`
+	// GUIDSource selects the item GUID: "rss_guid" (default) prefers the
+	// syndication GUID and falls back to the episode GUID, "guid" always
+	// uses the episode GUID.
+	GUIDSource string `toml:"guid_source"`
+	// SubtitleLength caps itunes:subtitle in characters (default 255).
+	SubtitleLength int `toml:"subtitle_length"`
+	// MaxDescriptionLength caps the plain item description in characters,
+	// cutting at a word boundary; content:encoded stays complete. Zero
+	// means no limit.
+	MaxDescriptionLength int `toml:"max_description_length"`
+	// StaleDays flags feeds whose latest episode is older than this many
+	// days (default 30).
+	StaleDays int `toml:"stale_days"`
+	// EpisodeTypes maps API episode kinds to itunes:episodeType values,
+	// overriding the built-in mapping.
+	EpisodeTypes map[string]string `toml:"episode_types"`
+
+	// APIBaseURL is where series JSON is fetched from, as
+	// <api_base_url>/<guid>.json.
+	APIBaseURL string `toml:"api_base_url"`
+	// UserAgent is sent with API requests (default "sumppi").
+	UserAgent string `toml:"user_agent"`
+	// APIToken enables bearer auth; SUMPPI_API_TOKEN overrides it.
+	APIToken string `toml:"api_token"`
+	// APIUsername and APIPassword enable basic auth when no token is set;
+	// SUMPPI_API_PASSWORD overrides the password.
+	APIUsername string `toml:"api_username"`
+	APIPassword string `toml:"api_password"`
+	// MaxResponseMB caps the size of an API response (default 50).
+	MaxResponseMB int `toml:"max_response_mb"`
+	// MaxFeedBytes refuses to write or upload a rendered feed larger than
+	// this, guarding against runaway output (default 20 MB).
+	MaxFeedBytes int64 `toml:"max_feed_bytes"`
+	// SkipMissingAudio drops episodes without an audio URL (default true).
+	SkipMissingAudio *bool `toml:"skip_missing_audio"`
+	// DedupeEpisodes keeps only the newest of episodes sharing a GUID
+	// (default true), as upstream occasionally repeats re-published items.
+	DedupeEpisodes *bool `toml:"dedupe_episodes"`
+
+	// AWSProfile selects a profile from the shared AWS config files;
+	// --profile overrides it. Unset uses the default credential chain,
+	// which also honors AWS_PROFILE.
+	AWSProfile string `toml:"aws_profile"`
+	// AssumeRoleARN, if set, is assumed via STS for all S3 operations.
+	AssumeRoleARN string `toml:"assume_role_arn"`
+	ExternalID    string `toml:"external_id"`
+
+	// ServerSideEncryption is "AES256" (SSE-S3) or "aws:kms" (SSE-KMS);
+	// KMSKeyID optionally selects the KMS key for the latter.
+	ServerSideEncryption string `toml:"server_side_encryption"`
+	KMSKeyID             string `toml:"kms_key_id"`
+
+	// Chapters uploads a podcast:chapters file for episodes with audio
+	// slices and links it from the feed.
+	Chapters bool `toml:"chapters"`
+	// MirrorCover copies the series cover into the bucket next to the feed
+	// and references the copy, so the feed does not depend on the upstream
+	// CDN. The original URL is kept if mirroring fails.
+	MirrorCover bool `toml:"mirror_cover"`
+
+	// S3Region selects the regional endpoint for public URLs, defaulting
+	// to AWS_REGION or AWS_DEFAULT_REGION.
+	S3Region string `toml:"s3_region"`
+	// PublicBaseURL replaces the bucket endpoint in public URLs.
+	PublicBaseURL string `toml:"public_base_url"`
+	// CDNURLs maps "bucket" or "bucket/prefix" to the CDN base URL that
+	// serves it, e.g. "feeds/podcasts" = "https://d123.cloudfront.net".
+	CDNURLs map[string]string `toml:"cdn_urls"`
+	// ForceUpload uploads feeds even when the stored copy is identical.
+	ForceUpload bool `toml:"force_upload"`
+	// BatchConcurrency is how many series generate/upload-all processes at
+	// once (default 4).
+	BatchConcurrency int `toml:"batch_concurrency"`
+	// UploadMaxAttempts is how many times a throttled S3 upload is tried
+	// before giving up (default 3).
+	UploadMaxAttempts int `toml:"upload_max_attempts"`
+	// CheckArtwork makes validation check that the cover is a JPEG or PNG.
+	// CheckArtworkDimensions also downloads it to check its size.
+	CheckArtwork           bool `toml:"check_artwork"`
+	CheckArtworkDimensions bool `toml:"check_artwork_dimensions"`
+	// TitlePrefix and TitleSuffix are added to every episode title, e.g.
+	// "[Archive]". Series settings take precedence.
+	TitlePrefix string `toml:"title_prefix"`
+	TitleSuffix string `toml:"title_suffix"`
+	// IncludeTags limits feeds to episodes with one of these tags;
+	// ExcludeTags drops episodes with any of them and wins over IncludeTags.
+	// A series' own lists replace these.
+	IncludeTags []string `toml:"include_tags"`
+	ExcludeTags []string `toml:"exclude_tags"`
+	// CategoryMap maps API categories to Apple Podcasts categories, given
+	// as "Category" or "Category/Subcategory". Once set, unmapped
+	// categories are dropped unless PassUnmappedCategories is true.
+	CategoryMap            map[string]string `toml:"category_map"`
+	PassUnmappedCategories bool              `toml:"pass_unmapped_categories"`
+	// OPMLS3Path, if set, is where the opml command uploads the index of
+	// all feeds.
+	OPMLS3Path string `toml:"opml_s3_path"`
+	// FeedFormat is "rss" (default) or "json" for JSON Feed 1.1.
+	FeedFormat string `toml:"feed_format"`
+	// MetadataConcurrency bounds concurrent API fetches when loading many
+	// series at once (default 5).
+	MetadataConcurrency int `toml:"metadata_concurrency"`
+	// AudioPackages lists audio_pkgs keys to use for the enclosure in order
+	// of preference, e.g. ["high", "medium"]. Episodes with none of them
+	// use their primary audio URL.
+	AudioPackages []string `toml:"audio_packages"`
+	// RankingWindow is the ranking the TUI sorts by and shows: "daily",
+	// "weekly" (default) or "monthly".
+	RankingWindow string `toml:"ranking_window"`
+	// FileMode is the octal permission of written files (default "0644").
+	FileMode string `toml:"file_mode"`
+	// MaxAgeDays omits episodes published more than this many days ago;
+	// 0 keeps all. Episodes with unparseable dates are not affected.
+	MaxAgeDays int `toml:"max_age_days"`
+}
+
+func (s Settings) s3Region() string {
+	for _, region := range []string{s.S3Region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION")} {
+		if region != "" {
+			return region
+		}
+	}
+	return ""
+}
+
+func (s Settings) skipMissingAudio() bool {
+	return s.SkipMissingAudio == nil || *s.SkipMissingAudio
+}
+
+// pubDateLocation returns the zone pubDate values are converted into, or
+// nil to keep them as returned by the API. The name is checked on load.
+func (s Settings) pubDateLocation() *time.Location {
+	if s.PubDateTimezone == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(s.PubDateTimezone)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
+func (s Settings) dedupeEpisodes() bool {
+	return s.DedupeEpisodes == nil || *s.DedupeEpisodes
+}
+
+// defaultKindPattern extracts numbering from kinds like "S2E5" or "12".
+const defaultKindPattern = `(?i)^\s*(?:s(?P<season>\d+))?\s*e?(?P<episode>\d+)\s*$`
+
+// validate reports the first invalid setting in the config and compiles
+// the per-series numbering patterns.
+func (c *SeriesConfig) validate() error {
+	switch c.Settings.ServerSideEncryption {
+	case "", "AES256", "aws:kms":
+	default:
+		return fmt.Errorf("server_side_encryption must be \"AES256\" or \"aws:kms\", got %q", c.Settings.ServerSideEncryption)
+	}
+	if c.Settings.KMSKeyID != "" && c.Settings.ServerSideEncryption != "aws:kms" {
+		return fmt.Errorf("kms_key_id requires server_side_encryption = \"aws:kms\"")
+	}
+
+	if c.Settings.FileMode != "" {
+		if mode, err := strconv.ParseUint(c.Settings.FileMode, 8, 32); err != nil || mode > 0777 {
+			return fmt.Errorf("file_mode must be an octal permission like \"0644\", got %q", c.Settings.FileMode)
+		}
+	}
+
+	if c.Settings.PubDateTimezone != "" {
+		if _, err := time.LoadLocation(c.Settings.PubDateTimezone); err != nil {
+			return fmt.Errorf("pubdate_timezone: %w", err)
+		}
+	}
+
+	switch c.Settings.RankingWindow {
+	case "", "daily", "weekly", "monthly":
+	default:
+		return fmt.Errorf("ranking_window must be \"daily\", \"weekly\" or \"monthly\", got %q", c.Settings.RankingWindow)
+	}
+
+	switch c.Settings.FeedFormat {
+	case "", feedFormatRSS, feedFormatJSON:
+	default:
+		return fmt.Errorf("feed_format must be \"rss\" or \"json\", got %q", c.Settings.FeedFormat)
+	}
+
+	switch c.Settings.InvalidDates {
+	case "", invalidDatesSkip, invalidDatesOmit:
+	default:
+		return fmt.Errorf("invalid_dates must be \"skip\" or \"omit\", got %q", c.Settings.InvalidDates)
+	}
+
+	switch c.Settings.GUIDSource {
+	case "", guidSourceRSSGUID, guidSourceGUID:
+	default:
+		return fmt.Errorf("guid_source must be \"rss_guid\" or \"guid\", got %q", c.Settings.GUIDSource)
+	}
+
+	for from, to := range c.Settings.CategoryMap {
+		if _, err := parseAppleCategory(to); err != nil {
+			return fmt.Errorf("category_map %q: %w", from, err)
+		}
+	}
+
+	for i := range c.Series {
+		series := &c.Series[i]
+		switch series.ITunesType {
+		case "", "episodic", "serial":
+		default:
+			return fmt.Errorf("series %s: itunes_type must be \"episodic\" or \"serial\", got %q", series.GUID, series.ITunesType)
+		}
+		if series.NewFeedURL != "" && !isAbsoluteURL(series.NewFeedURL) {
+			return fmt.Errorf("series %s: new_feed_url must be an absolute http(s) URL, got %q", series.GUID, series.NewFeedURL)
+		}
+		if series.Locked {
+			if addr, err := mail.ParseAddress(series.LockedOwner); err != nil || addr.Address != series.LockedOwner {
+				return fmt.Errorf("series %s: locked requires locked_owner to be an email address, got %q", series.GUID, series.LockedOwner)
+			}
+		}
+		if err := validateExtraElements(series.ExtraElements); err != nil {
+			return fmt.Errorf("series %s: %w", series.GUID, err)
+		}
+		if err := series.validateSchedule(); err != nil {
+			return fmt.Errorf("series %s: %w", series.GUID, err)
+		}
+		if err := series.compileNumbering(); err != nil {
+			return fmt.Errorf("series %s: %w", series.GUID, err)
+		}
+	}
+	return nil
+}
+
+// validateSchedule checks the polling hints and normalizes day names to the
+// capitalization RSS requires.
+func (s *Series) validateSchedule() error {
+	if s.TTL < 0 {
+		return fmt.Errorf("ttl must not be negative, got %d", s.TTL)
+	}
+	for _, hour := range s.SkipHours {
+		if hour < 0 || hour > 23 {
+			return fmt.Errorf("skip_hours must be between 0 and 23, got %d", hour)
+		}
+	}
+	for i, day := range s.SkipDays {
+		weekday, ok := parseWeekday(day)
+		if !ok {
+			return fmt.Errorf("skip_days must be weekday names like \"Monday\", got %q", day)
+		}
+		s.SkipDays[i] = weekday.String()
+	}
+	return nil
+}
+
+func parseWeekday(name string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(name, day.String()) {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// titleAffixes returns the episode title prefix and suffix for the series,
+// falling back to the global settings.
+func (s Series) titleAffixes(settings Settings) (prefix, suffix string) {
+	prefix, suffix = settings.TitlePrefix, settings.TitleSuffix
+	if s.TitlePrefix != "" {
+		prefix = s.TitlePrefix
+	}
+	if s.TitleSuffix != "" {
+		suffix = s.TitleSuffix
+	}
+	return prefix, suffix
+}
+
+// tagFilters returns the include and exclude tags for the series, each
+// falling back to the global list when the series does not set one.
+func (s Series) tagFilters(settings Settings) (include, exclude []string) {
+	include, exclude = settings.IncludeTags, settings.ExcludeTags
+	if len(s.IncludeTags) > 0 {
+		include = s.IncludeTags
+	}
+	if len(s.ExcludeTags) > 0 {
+		exclude = s.ExcludeTags
+	}
+	return include, exclude
+}
+
+func (s *Series) compileNumbering() error {
+	pattern := s.NumberingPattern
+	switch s.NumberingSource {
+	case "":
+		if pattern != "" {
+			return fmt.Errorf("numbering_pattern requires numbering_source")
+		}
+		return nil
+	case "title":
+		if pattern == "" {
+			return fmt.Errorf("numbering_source \"title\" requires numbering_pattern")
+		}
+	case "kind":
+		if pattern == "" {
+			pattern = defaultKindPattern
+		}
+	default:
+		return fmt.Errorf("numbering_source must be \"title\" or \"kind\", got %q", s.NumberingSource)
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid numbering_pattern: %w", err)
+	}
+	if re.SubexpIndex("season") < 0 && re.SubexpIndex("episode") < 0 {
+		return fmt.Errorf("numbering_pattern must have a named group \"season\" or \"episode\"")
+	}
+	s.numberingRegexp = re
+	return nil
 }
 
+// episodeNumbering extracts the season and episode numbers for an episode.
+// Zero means the number is unknown.
+func (s Series) episodeNumbering(episode Episode) (season, number int) {
+	if s.numberingRegexp == nil {
+		return 0, 0
+	}
+
+	value := episode.Title
+	if s.NumberingSource == "kind" {
+		value = episode.Kind
+	}
+	match := s.numberingRegexp.FindStringSubmatch(value)
+	if match == nil {
+		return 0, 0
+	}
+
+	group := func(name string) int {
+		i := s.numberingRegexp.SubexpIndex(name)
+		if i < 0 {
+			return 0
+		}
+		n, _ := strconv.Atoi(match[i])
+		return n
+	}
+	return group("season"), group("episode")
+}
+
+func isAbsoluteURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// destinations lists every S3 path the feed is uploaded to.
+func (s Series) destinations() []string {
+	return append([]string{s.S3Path}, s.MirrorPaths...)
+}
+
+func (s Settings) staleThreshold() time.Duration {
+	days := s.StaleDays
+	if days <= 0 {
+		days = defaultStaleDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+const defaultStaleDays = 30
+
 type Series struct {
 	GUID   string `toml:"guid"`
 	S3Path string `toml:"s3_path"`
+	// MirrorPaths are extra destinations that receive the same feed on
+	// upload. S3Path stays the primary used for URLs and local filenames.
+	MirrorPaths []string `toml:"mirror_s3_paths"`
+	// ITunesType is "episodic" (default) or "serial".
+	ITunesType string `toml:"itunes_type"`
+	// Explicit marks the podcast as containing explicit content.
+	Explicit bool `toml:"explicit"`
+	// NewFeedURL announces that the feed has moved to this address.
+	NewFeedURL string `toml:"new_feed_url"`
+	// Complete marks a finished podcast that will get no new episodes.
+	Complete bool `toml:"complete"`
+	// Locked asks other platforms not to import the feed; LockedOwner is
+	// the email address that may authorize a move and is required with it.
+	Locked      bool   `toml:"locked"`
+	LockedOwner string `toml:"locked_owner"`
+	// TTL, SkipHours and SkipDays tell clients how often to poll: the
+	// minutes a copy stays fresh, GMT hours 0-23 and weekday names
+	// ("Monday") when the feed never changes.
+	TTL       int      `toml:"ttl"`
+	SkipHours []int    `toml:"skip_hours"`
+	SkipDays  []string `toml:"skip_days"`
+	// NumberingSource is "title" or "kind": the episode field that
+	// NumberingPattern is matched against to find itunes:season and
+	// itunes:episode. The pattern uses named groups "season" and "episode";
+	// "kind" has a default pattern accepting values like "S2E5" or "12".
+	NumberingSource  string `toml:"numbering_source"`
+	NumberingPattern string `toml:"numbering_pattern"`
+	// BlockedEpisodes lists episode GUIDs to hide from podcast directories.
+	BlockedEpisodes []string `toml:"blocked_episodes"`
+	// TitleOverride and DescriptionOverride replace the channel title and
+	// description from the API when set.
+	TitleOverride       string `toml:"title_override"`
+	DescriptionOverride string `toml:"description_override"`
+	// Summary replaces the channel itunes:summary, which otherwise comes
+	// from the description.
+	Summary string `toml:"summary"`
+	// TitlePrefix and TitleSuffix override the global episode title affixes.
+	TitlePrefix string `toml:"title_prefix"`
+	TitleSuffix string `toml:"title_suffix"`
+	// GUIDPrefix is prepended to every item GUID so feeds that share
+	// episodes still have unique GUIDs, e.g. "archive-".
+	GUIDPrefix string `toml:"guid_prefix"`
+	// IncludeTags and ExcludeTags replace the global tag filters when set.
+	IncludeTags []string `toml:"include_tags"`
+	ExcludeTags []string `toml:"exclude_tags"`
+	// ExtraElements are added to the channel verbatim, for tags sumppi has
+	// no setting for.
+	ExtraElements []ExtraElement `toml:"extra_elements"`
+
+	// numberingRegexp is compiled from NumberingPattern on config load
+	numberingRegexp *regexp.Regexp
 }
 
 type APIResponse struct {
@@ -58,8 +491,8 @@ type Episode struct {
 	PublicationDate     string               `json:"publication_date"`
 	RSSGUID             string               `json:"rss_guid"`
 	AudioURL            string               `json:"audio_url"`
-	AudioDuration       int                  `json:"audio_duration"`
-	AudioLength         int                  `json:"audio_length"`
+	AudioDuration       flexInt              `json:"audio_duration"`
+	AudioLength         flexInt              `json:"audio_length"`
 	AudioSample         AudioSample          `json:"audio_sample"`
 	AudioPkgs           map[string]string    `json:"audio_pkgs"`
 	LastModified        string               `json:"last_modified"`
@@ -75,18 +508,54 @@ type Episode struct {
 	SquarePhotoAuthor   *string              `json:"square_photo_author"`
 	H                   *string              `json:"h"`
 	Kind                string               `json:"kind"`
+	Blocked             bool                 `json:"blocked"`
+}
+
+// stringValue returns the pointed-to string, or "" for nil. The API uses
+// null for many optional fields, so feed code reads them through this.
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
 }
 
+func (e Episode) htmlDescription() string { return stringValue(e.HTMLDescription) }
+func (e Episode) squareCoverURL() string  { return stringValue(e.SquareCoverURL) }
+
+func (d SeriesData) htmlDescription() string { return stringValue(d.HTMLDescription) }
+
 type AudioSample struct {
-	AudioURL      string `json:"audio_url"`
-	AudioDuration int    `json:"audio_duration"`
-	AudioLength   int    `json:"audio_length"`
+	AudioURL      string  `json:"audio_url"`
+	AudioDuration flexInt `json:"audio_duration"`
+	AudioLength   flexInt `json:"audio_length"`
 }
 
 type AudioSlice struct {
-	URL   string `json:"url"`
-	Start int    `json:"start"`
-	End   int    `json:"end"`
+	URL   string  `json:"url"`
+	Start flexInt `json:"start"`
+	End   flexInt `json:"end"`
+}
+
+// flexInt decodes a JSON number or a numeric string, since the API has sent
+// audio sizes and durations both ways. Null, empty and unparseable values
+// decode as 0 rather than failing the whole response.
+type flexInt int
+
+func (n *flexInt) UnmarshalJSON(data []byte) error {
+	value := strings.Trim(string(data), `"`)
+	if value == "" || value == "null" {
+		*n = 0
+		return nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		slog.Warn("ignoring non-numeric value in API response", "value", string(data))
+		*n = 0
+		return nil
+	}
+	*n = flexInt(f)
+	return nil
 }
 
 type AvailabilityPeriod struct {
@@ -102,40 +571,270 @@ type Rankings struct {
 	Monthly int `json:"monthly"`
 }
 
-func fetchSeriesData(guid string) (*SeriesData, error) {
-	url := fmt.Sprintf("https://appdata.richie.fi/books/feeds/v3/Nelonen/podcast_series/%s.json", guid)
+// SeriesNotFoundError means the API has no series with the GUID, usually
+// because it was removed upstream.
+type SeriesNotFoundError struct {
+	GUID string
+}
+
+func (e *SeriesNotFoundError) Error() string {
+	return fmt.Sprintf("series %s not found upstream — was it removed?", e.GUID)
+}
+
+const (
+	defaultUserAgent     = "sumppi"
+	defaultAPIBaseURL    = "https://appdata.richie.fi/books/feeds/v3/Nelonen/podcast_series"
+	defaultMaxResponseMB = 50
+	defaultMaxFeedBytes  = 20 << 20
+)
+
+const (
+	feedFormatRSS  = "rss"
+	feedFormatJSON = "json"
+)
+
+// Values of invalid_dates.
+const (
+	invalidDatesSkip = "skip"
+	invalidDatesOmit = "omit"
+)
+
+// Values of guid_source.
+const (
+	guidSourceRSSGUID = "rss_guid"
+	guidSourceGUID    = "guid"
+)
+
+// feedContentType is the MIME type feeds are uploaded with.
+func (s Settings) feedContentType() string {
+	if s.FeedFormat == feedFormatJSON {
+		return "application/json"
+	}
+	return "application/rss+xml"
+}
+
+// feedExtension is the file extension of locally written feeds.
+func (s Settings) feedExtension() string {
+	if s.FeedFormat == feedFormatJSON {
+		return ".json"
+	}
+	return ".rss"
+}
+
+const defaultMetadataConcurrency = 5
+
+func (s Settings) metadataConcurrency() int {
+	if s.MetadataConcurrency <= 0 {
+		return defaultMetadataConcurrency
+	}
+	return s.MetadataConcurrency
+}
+
+const defaultFileMode os.FileMode = 0644
+
+// fileMode parses FileMode, which validate has already checked.
+func (s Settings) fileMode() os.FileMode {
+	mode, err := strconv.ParseUint(s.FileMode, 8, 32)
+	if s.FileMode == "" || err != nil {
+		return defaultFileMode
+	}
+	return os.FileMode(mode)
+}
+
+const defaultBatchConcurrency = 4
+
+const defaultUploadMaxAttempts = 3
+
+func (s Settings) uploadMaxAttempts() int {
+	if s.UploadMaxAttempts <= 0 {
+		return defaultUploadMaxAttempts
+	}
+	return s.UploadMaxAttempts
+}
+
+func (s Settings) batchConcurrency() int {
+	if s.BatchConcurrency <= 0 {
+		return defaultBatchConcurrency
+	}
+	return s.BatchConcurrency
+}
+
+func (s Settings) maxResponseBytes() int64 {
+	mb := s.MaxResponseMB
+	if mb <= 0 {
+		mb = defaultMaxResponseMB
+	}
+	return int64(mb) << 20
+}
+
+func (s Settings) apiBaseURL() string {
+	if s.APIBaseURL == "" {
+		return defaultAPIBaseURL
+	}
+	return strings.TrimRight(s.APIBaseURL, "/")
+}
+
+// httpClient makes every outgoing HTTP request; tests replace it.
+var httpClient = http.DefaultClient
+
+func (s Settings) maxFeedBytes() int64 {
+	if s.MaxFeedBytes <= 0 {
+		return defaultMaxFeedBytes
+	}
+	return s.MaxFeedBytes
+}
 
-	resp, err := http.Get(url)
+// setAPIHeaders adds the user agent, Accept-Encoding and any configured
+// credentials. Credentials are deliberately kept out of the logs.
+//
+// Setting Accept-Encoding explicitly stops the transport from decompressing
+// the response, so fetchSeriesJSON decodes it itself. That works the same
+// with any http.Client, including ones whose transport does not ask for gzip.
+func setAPIHeaders(req *http.Request, settings Settings) {
+	userAgent := settings.UserAgent
+	if userAgent == "" {
+		userAgent = defaultUserAgent
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	token := settings.APIToken
+	if envToken := os.Getenv("SUMPPI_API_TOKEN"); envToken != "" {
+		token = envToken
+	}
+	password := settings.APIPassword
+	if envPassword := os.Getenv("SUMPPI_API_PASSWORD"); envPassword != "" {
+		password = envPassword
+	}
+
+	switch {
+	case token != "":
+		req.Header.Set("Authorization", "Bearer "+token)
+	case settings.APIUsername != "":
+		req.SetBasicAuth(settings.APIUsername, password)
+	}
+}
+
+func fetchSeriesData(ctx context.Context, guid string, settings Settings) (*SeriesData, error) {
+	raw, err := fetchSeriesJSON(ctx, guid, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	var apiResponse APIResponse
+	if err := json.Unmarshal(raw, &apiResponse); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON response: %w", err)
+	}
+
+	return &apiResponse.Data, nil
+}
+
+// fetchSeriesJSON returns the undecoded API response for the series.
+func fetchSeriesJSON(ctx context.Context, guid string, settings Settings) ([]byte, error) {
+	url := fmt.Sprintf("%s/%s.json", settings.apiBaseURL(), guid)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	setAPIHeaders(req, settings)
+
+	slog.Debug("fetching series data", "url", url)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch series data: %w", err)
 	}
 	defer resp.Body.Close()
+	slog.Debug("series data response", "url", url, "status", resp.StatusCode, "decompressed", resp.Uncompressed)
 
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, &SeriesNotFoundError{GUID: guid}
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("API returned status code %d", resp.StatusCode)
 	}
 
-	var apiResponse APIResponse
-	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
-		return nil, fmt.Errorf("failed to decode JSON response: %w", err)
+	body, err := decodeContentEncoding(resp)
+	if err != nil {
+		return nil, err
 	}
 
-	return &apiResponse.Data, nil
+	// Allow one byte past the limit so an oversized body can be detected.
+	// The limit applies to the decompressed size.
+	maxBytes := settings.maxResponseBytes()
+	raw, err := io.ReadAll(io.LimitReader(body, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API response: %w", err)
+	}
+	if int64(len(raw)) > maxBytes {
+		return nil, fmt.Errorf("API response exceeds the %d MB limit", maxBytes>>20)
+	}
+
+	return raw, nil
+}
+
+// decodeContentEncoding returns the response body, decompressing it if it
+// is gzipped. Any other encoding is passed through as is with a warning;
+// if the body really is encoded, parsing it reports the problem.
+func decodeContentEncoding(resp *http.Response) (io.Reader, error) {
+	switch encoding := strings.ToLower(resp.Header.Get("Content-Encoding")); encoding {
+	case "", "identity":
+		return resp.Body, nil
+	case "gzip":
+		zr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress API response: %w", err)
+		}
+		return zr, nil
+	default:
+		slog.Warn("API response has unexpected content encoding, reading it as is", "encoding", encoding)
+		return resp.Body, nil
+	}
+}
+
+// publicationDateLayouts lists the date formats seen from the API, tried in
+// order. Layouts without a zone are interpreted as UTC.
+var publicationDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+func parsePublicationDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range publicationDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date format: %q", value)
+}
+
+func getLatestEpisodeDate(episodes []Episode, now time.Time) (string, error) {
+	latestTime, err := latestEpisodeTime(episodes, now)
+	if err != nil {
+		return "", err
+	}
+
+	return latestTime.Format("Jan 2, 2006"), nil
 }
 
-func getLatestEpisodeDate(episodes []Episode) (string, error) {
+// latestEpisodeTime returns the publication time of the newest episode,
+// ignoring episodes scheduled more than a week after now.
+func latestEpisodeTime(episodes []Episode, now time.Time) (time.Time, error) {
 	if len(episodes) == 0 {
-		return "", fmt.Errorf("no episodes found")
+		return time.Time{}, fmt.Errorf("no episodes found")
 	}
 
-	now := time.Now()
 	oneWeekFromNow := now.Add(7 * 24 * time.Hour)
 
-	var latestDate string
+	var found bool
 	var latestTime time.Time
 
 	for _, episode := range episodes {
-		episodeTime, err := time.Parse(time.RFC3339, episode.PublicationDate)
+		episodeTime, err := parsePublicationDate(episode.PublicationDate)
 		if err != nil {
 			continue // Skip episodes with invalid dates
 		}
@@ -145,15 +844,15 @@ func getLatestEpisodeDate(episodes []Episode) (string, error) {
 			continue
 		}
 
-		if latestDate == "" || episodeTime.After(latestTime) {
-			latestDate = episode.PublicationDate
+		if !found || episodeTime.After(latestTime) {
+			found = true
 			latestTime = episodeTime
 		}
 	}
 
-	if latestDate == "" {
-		return "", fmt.Errorf("no valid episodes found")
+	if !found {
+		return time.Time{}, fmt.Errorf("no valid episodes found")
 	}
 
-	return latestTime.Format("Jan 2, 2006"), nil
+	return latestTime, nil
 }
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// apiServer serves handler as the series API and returns settings that
+// point at it.
+func apiServer(t *testing.T, handler http.HandlerFunc) Settings {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return Settings{APIBaseURL: srv.URL}
+}
+
+func TestFetchSeriesDataNotFound(t *testing.T) {
+	settings := apiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	_, err := fetchSeriesData(context.Background(), "removed-guid", settings)
+	var notFound *SeriesNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("fetchSeriesData() error = %v, want SeriesNotFoundError", err)
+	}
+	if notFound.GUID != "removed-guid" {
+		t.Errorf("SeriesNotFoundError.GUID = %q, want %q", notFound.GUID, "removed-guid")
+	}
+}
+
+func TestFetchSeriesDataPath(t *testing.T) {
+	var path string
+	settings := apiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Write([]byte(`{"data": {"guid": "abc", "title": "Show"}}`))
+	})
+
+	data, err := fetchSeriesData(context.Background(), "abc", settings)
+	if err != nil {
+		t.Fatalf("fetchSeriesData() error = %v", err)
+	}
+	if path != "/abc.json" {
+		t.Errorf("requested path = %q, want /abc.json", path)
+	}
+	if data.Title != "Show" {
+		t.Errorf("Title = %q, want %q", data.Title, "Show")
+	}
+}
+
+func TestFetchSeriesDataGzip(t *testing.T) {
+	var acceptEncoding string
+	settings := apiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		zw.Write([]byte(`{"data": {"guid": "abc", "title": "Compressed"}}`))
+		zw.Close()
+	})
+
+	data, err := fetchSeriesData(context.Background(), "abc", settings)
+	if err != nil {
+		t.Fatalf("fetchSeriesData() error = %v", err)
+	}
+	if acceptEncoding != "gzip" {
+		t.Errorf("Accept-Encoding = %q, want gzip", acceptEncoding)
+	}
+	if data.Title != "Compressed" {
+		t.Errorf("Title = %q, want %q", data.Title, "Compressed")
+	}
+}
+
+func TestFetchSeriesDataUnknownEncoding(t *testing.T) {
+	settings := apiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "x-custom")
+		w.Write([]byte(`{"data": {"guid": "abc", "title": "Plain"}}`))
+	})
+
+	data, err := fetchSeriesData(context.Background(), "abc", settings)
+	if err != nil {
+		t.Fatalf("fetchSeriesData() error = %v", err)
+	}
+	if data.Title != "Plain" {
+		t.Errorf("Title = %q, want %q", data.Title, "Plain")
+	}
+}
+
+func TestParsePublicationDate(t *testing.T) {
+	helsinki := time.FixedZone("", 3*60*60)
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"2024-05-30T06:00:00Z", time.Date(2024, 5, 30, 6, 0, 0, 0, time.UTC)},
+		{"2024-05-30T09:00:00+03:00", time.Date(2024, 5, 30, 9, 0, 0, 0, helsinki)},
+		{"2024-05-30 09:00:00+03:00", time.Date(2024, 5, 30, 9, 0, 0, 0, helsinki)},
+		{"2024-05-30T06:00:00", time.Date(2024, 5, 30, 6, 0, 0, 0, time.UTC)},
+		{"2024-05-30 06:00:00", time.Date(2024, 5, 30, 6, 0, 0, 0, time.UTC)},
+		{"2024-05-30", time.Date(2024, 5, 30, 0, 0, 0, 0, time.UTC)},
+		{"  2024-05-30  ", time.Date(2024, 5, 30, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := parsePublicationDate(tt.value)
+		if err != nil {
+			t.Errorf("parsePublicationDate(%q) error = %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parsePublicationDate(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", "yesterday", "30.5.2024"} {
+		if _, err := parsePublicationDate(value); err == nil {
+			t.Errorf("parsePublicationDate(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+func TestFetchSeriesDataHeaders(t *testing.T) {
+	tests := []struct {
+		name          string
+		settings      Settings
+		env           map[string]string
+		wantUserAgent string
+		wantAuth      string
+	}{
+		{"defaults", Settings{}, nil, defaultUserAgent, ""},
+		{"user agent", Settings{UserAgent: "feeds/1.0"}, nil, "feeds/1.0", ""},
+		{"bearer token", Settings{APIToken: "secret"}, nil, defaultUserAgent, "Bearer secret"},
+		{"token from env", Settings{APIToken: "config"}, map[string]string{"SUMPPI_API_TOKEN": "env"}, defaultUserAgent, "Bearer env"},
+		{"basic auth", Settings{APIUsername: "user", APIPassword: "pass"}, nil, defaultUserAgent, "Basic dXNlcjpwYXNz"},
+		{"token wins over basic auth", Settings{APIToken: "secret", APIUsername: "user"}, nil, defaultUserAgent, "Bearer secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SUMPPI_API_TOKEN", "")
+			t.Setenv("SUMPPI_API_PASSWORD", "")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			var header http.Header
+			settings := apiServer(t, func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header.Clone()
+				w.Write([]byte(`{"data": {"guid": "abc"}}`))
+			})
+			tt.settings.APIBaseURL = settings.APIBaseURL
+
+			if _, err := fetchSeriesData(context.Background(), "abc", tt.settings); err != nil {
+				t.Fatalf("fetchSeriesData() error = %v", err)
+			}
+			if got := header.Get("User-Agent"); got != tt.wantUserAgent {
+				t.Errorf("User-Agent = %q, want %q", got, tt.wantUserAgent)
+			}
+			if got := header.Get("Authorization"); got != tt.wantAuth {
+				t.Errorf("Authorization = %q, want %q", got, tt.wantAuth)
+			}
+		})
+	}
+}
+
+func TestFetchSeriesDataSizeLimit(t *testing.T) {
+	// Leading whitespace keeps the body valid JSON, so only the limit fails it
+	body := strings.Repeat(" ", 2<<20) + `{"data": {"guid": "abc"}}`
+	tests := []struct {
+		name string
+		gzip bool
+	}{
+		{"plain", false},
+		{"decompressed size", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := apiServer(t, func(w http.ResponseWriter, r *http.Request) {
+				if tt.gzip {
+					w.Header().Set("Content-Encoding", "gzip")
+					zw := gzip.NewWriter(w)
+					defer zw.Close()
+					io.WriteString(zw, body)
+					return
+				}
+				io.WriteString(w, body)
+			})
+			settings.MaxResponseMB = 1
+
+			_, err := fetchSeriesData(context.Background(), "abc", settings)
+			if err == nil || !strings.Contains(err.Error(), "exceeds the 1 MB limit") {
+				t.Errorf("fetchSeriesData() error = %v, want the size limit error", err)
+			}
+
+			settings.MaxResponseMB = 3
+			if _, err := fetchSeriesData(context.Background(), "abc", settings); err != nil {
+				t.Errorf("fetchSeriesData() under the limit error = %v", err)
+			}
+		})
+	}
+}
+
+func TestEpisodeNumbering(t *testing.T) {
+	tests := []struct {
+		name        string
+		series      Series
+		episode     Episode
+		wantSeason  int
+		wantEpisode int
+	}{
+		{
+			"both groups from title",
+			Series{NumberingSource: "title", NumberingPattern: `Season (?P<season>\d+), Episode (?P<episode>\d+)`},
+			Episode{Title: "Season 2, Episode 14: The finale"}, 2, 14,
+		},
+		{
+			"episode group only",
+			Series{NumberingSource: "title", NumberingPattern: `^#(?P<episode>\d+)`},
+			Episode{Title: "#42 Interview"}, 0, 42,
+		},
+		{
+			"non-matching title",
+			Series{NumberingSource: "title", NumberingPattern: `^#(?P<episode>\d+)`},
+			Episode{Title: "Bonus interview"}, 0, 0,
+		},
+		{"kind with season", Series{NumberingSource: "kind"}, Episode{Kind: "S3E7"}, 3, 7},
+		{"kind number only", Series{NumberingSource: "kind"}, Episode{Kind: "12"}, 0, 12},
+		{"kind not numeric", Series{NumberingSource: "kind"}, Episode{Kind: "trailer"}, 0, 0},
+		{"disabled", Series{}, Episode{Title: "#42", Kind: "12"}, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			series := tt.series
+			if err := series.compileNumbering(); err != nil {
+				t.Fatalf("compileNumbering() error = %v", err)
+			}
+			season, episode := series.episodeNumbering(tt.episode)
+			if season != tt.wantSeason || episode != tt.wantEpisode {
+				t.Errorf("episodeNumbering() = %d, %d, want %d, %d", season, episode, tt.wantSeason, tt.wantEpisode)
+			}
+		})
+	}
+}
+
+func TestCompileNumberingErrors(t *testing.T) {
+	tests := []Series{
+		{NumberingSource: "title"},
+		{NumberingSource: "title", NumberingPattern: `(?P<episode>\d+`},
+		{NumberingPattern: `(?P<episode>\d+)`},
+		{NumberingSource: "description", NumberingPattern: `(?P<episode>\d+)`},
+	}
+	for _, series := range tests {
+		if err := series.compileNumbering(); err == nil {
+			t.Errorf("compileNumbering(%q, %q) succeeded, want an error", series.NumberingSource, series.NumberingPattern)
+		}
+	}
+}
+
+func TestFetchSeriesDataTolerantDecoding(t *testing.T) {
+	settings := apiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {"guid": "abc", "unexpected": {"nested": true}, "episodes": [
+			{"guid": "number", "audio_length": 1000, "audio_duration": 61.5, "new_field": [1, 2]},
+			{"guid": "string", "audio_length": "2000", "audio_duration": "62"},
+			{"guid": "empty", "audio_length": "", "audio_duration": null},
+			{"guid": "garbage", "audio_length": "n/a", "audio_duration": "1:02",
+			 "audio_sample": {"audio_length": "300"}, "audio_slices": [{"start": "0", "end": 30}]}
+		]}}`))
+	})
+
+	data, err := fetchSeriesData(context.Background(), "abc", settings)
+	if err != nil {
+		t.Fatalf("fetchSeriesData() error = %v", err)
+	}
+	want := []struct {
+		length, duration flexInt
+	}{{1000, 61}, {2000, 62}, {0, 0}, {0, 0}}
+	for i, w := range want {
+		episode := data.Episodes[i]
+		if episode.AudioLength != w.length || episode.AudioDuration != w.duration {
+			t.Errorf("%s: length, duration = %d, %d, want %d, %d", episode.GUID, episode.AudioLength, episode.AudioDuration, w.length, w.duration)
+		}
+	}
+	garbage := data.Episodes[3]
+	if garbage.AudioSample.AudioLength != 300 || garbage.AudioSlices[0].End != 30 {
+		t.Errorf("nested numeric fields = %+v, %+v", garbage.AudioSample, garbage.AudioSlices)
+	}
+}
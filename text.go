@@ -0,0 +1,64 @@
+package main
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	htmlCommentPattern   = regexp.MustCompile(`(?s)<!--.*?-->`)
+	htmlBreakPattern     = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|li|h[1-6])\s*>`)
+	htmlTagPattern       = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+	blankLinesPattern    = regexp.MustCompile(`\n\s*\n\s*`)
+	trailingSpacePattern = regexp.MustCompile(`[ \t]+\n`)
+)
+
+// stripHTML converts an HTML fragment into plain text. Line breaks and
+// block-level closing tags become newlines, other tags are removed and
+// entities are decoded. Text without tags passes through unchanged apart
+// from entity decoding.
+func stripHTML(s string) string {
+	s = htmlCommentPattern.ReplaceAllString(s, "")
+	s = htmlBreakPattern.ReplaceAllString(s, "\n")
+	s = htmlTagPattern.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	s = trailingSpacePattern.ReplaceAllString(s, "\n")
+	s = blankLinesPattern.ReplaceAllString(s, "\n\n")
+	return strings.TrimSpace(s)
+}
+
+// normalizeWhitespace collapses runs of whitespace into single spaces.
+func normalizeWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// truncateText shortens s to at most maxLen runes, cutting at a word
+// boundary where possible and appending an ellipsis.
+func truncateText(s string, maxLen int) string {
+	if maxLen <= 0 || utf8.RuneCountInString(s) <= maxLen {
+		return s
+	}
+
+	runes := []rune(s)
+	cut := string(runes[:maxLen-1])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
+
+// firstSentence returns the text up to and including the first sentence
+// terminator, or the whole text if there is none.
+func firstSentence(s string) string {
+	for i, r := range s {
+		if r == '.' || r == '!' || r == '?' {
+			next := i + utf8.RuneLen(r)
+			if next == len(s) || s[next] == ' ' {
+				return s[:next]
+			}
+		}
+	}
+	return s
+}
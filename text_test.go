@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestStripHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain text", "Just text", "Just text"},
+		{"nested tags", "<p>The <b><i>big</i> story</b> today</p>", "The big story today"},
+		{"entities", "Fish &amp; chips &lt;3 &quot;tasty&quot; &#8211; caf&eacute;", `Fish & chips <3 "tasty" – café`},
+		{"self-closing tags", "One<br/>Two<br />Three<img src=\"x.jpg\"/>", "One\nTwo\nThree"},
+		{"paragraphs", "<p>First</p>\n<p>Second</p>", "First\n\nSecond"},
+		{"comments", "Before<!-- <p>hidden</p> -->After", "BeforeAfter"},
+		{"attributes with slashes", `<a href="https://example.com/a">link</a>`, "link"},
+		{"less-than in text", "3 < 4 and 5 > 2", "3 < 4 and 5 > 2"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripHTML(tt.in); got != tt.want {
+				t.Errorf("stripHTML(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     string
+		maxLen int
+		want   string
+	}{
+		{"disabled", "Hello world again", 0, "Hello world again"},
+		{"exactly at the limit", "Hello world", 11, "Hello world"},
+		{"word boundary", "Hello world again", 11, "Hello…"},
+		{"trailing punctuation dropped", "Hello, world again", 12, "Hello…"},
+		{"no space to break at", "Supercalifragilistic", 6, "Super…"},
+		{"multibyte at a word boundary", "Hyvää päivää ystävät", 10, "Hyvää…"},
+		{"multibyte without spaces", "ääääääääää", 5, "ääää…"},
+		{"emoji", "🎙️🎧🎙️🎧🎙️🎧", 4, "🎙️🎧…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateText(tt.in, tt.maxLen)
+			if got != tt.want {
+				t.Errorf("truncateText(%q, %d) = %q, want %q", tt.in, tt.maxLen, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("truncateText(%q, %d) split a rune: %q", tt.in, tt.maxLen, got)
+			}
+			if n := utf8.RuneCountInString(got); tt.maxLen > 0 && n > tt.maxLen {
+				t.Errorf("truncateText(%q, %d) is %d characters long", tt.in, tt.maxLen, n)
+			}
+		})
+	}
+}
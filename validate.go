@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+type issueSeverity int
+
+const (
+	severityWarning issueSeverity = iota
+	severityError
+)
+
+func (s issueSeverity) String() string {
+	if s == severityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// feedIssue is a single problem found by validateFeed.
+type feedIssue struct {
+	Severity issueSeverity
+	Message  string
+}
+
+func (i feedIssue) String() string {
+	return fmt.Sprintf("%s: %s", i.Severity, i.Message)
+}
+
+// validateFeed checks the feed against the RSS 2.0 and Apple Podcasts
+// requirements. Errors are elements directories reject feeds for missing;
+// warnings are recommended elements.
+func validateFeed(feed *RSSFeed) []feedIssue {
+	var issues []feedIssue
+	add := func(severity issueSeverity, format string, args ...any) {
+		issues = append(issues, feedIssue{Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	ch := feed.Channel
+	if ch.Title == "" {
+		add(severityError, "channel title is missing")
+	}
+	if ch.Description == "" {
+		add(severityError, "channel description is missing")
+	}
+	if ch.ITunesImage.Href == "" {
+		add(severityError, "channel itunes:image is missing")
+	}
+	if len(ch.ITunesCategories) == 0 {
+		add(severityError, "channel itunes:category is missing")
+	}
+	if ch.ITunesOwner == nil || ch.ITunesOwner.Name == "" {
+		add(severityWarning, "channel itunes:owner is missing")
+	}
+	if ch.Link == "" {
+		add(severityWarning, "channel link is missing")
+	}
+	if len(ch.Items) == 0 {
+		add(severityWarning, "feed has no episodes")
+	}
+
+	for _, item := range ch.Items {
+		name := item.Title
+		if name == "" {
+			name = item.GUID.Value
+			add(severityError, "episode %s has no title", name)
+		}
+		if item.Enclosure.URL == "" {
+			add(severityError, "episode %q has no enclosure URL", name)
+		}
+		if item.Enclosure.Length == "" {
+			add(severityWarning, "episode %q has no enclosure length", name)
+		}
+		if item.GUID.Value == "" {
+			add(severityWarning, "episode %q has no GUID", name)
+		}
+		if item.PubDate == "" {
+			add(severityWarning, "episode %q has no pubDate", name)
+		}
+	}
+
+	return issues
+}
+
+func hasValidationErrors(issues []feedIssue) bool {
+	for _, issue := range issues {
+		if issue.Severity == severityError {
+			return true
+		}
+	}
+	return false
+}
+
+// validateSeries fetches and builds the feed for series the same way an
+// upload would, and validates it. Problems that would stop the upload, such
+// as exceeding max_feed_bytes, are reported as errors, and episodes left out
+// of the feed as warnings.
+func validateSeries(ctx context.Context, series Series, settings Settings) ([]feedIssue, error) {
+	seriesData, err := fetchSeries(ctx, series, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	var result seriesResult
+	feed, _ := assembleSeriesFeed(seriesData, series, settings, &result)
+	issues := validateFeed(feed)
+	if _, err := marshalSeriesFeed(feed, settings); err != nil {
+		issues = append(issues, feedIssue{Severity: severityError, Message: err.Error()})
+	}
+	for _, warning := range result.Warnings {
+		issues = append(issues, feedIssue{Severity: severityWarning, Message: warning})
+	}
+	if settings.CheckArtwork || settings.CheckArtworkDimensions {
+		issues = append(issues, checkArtwork(ctx, seriesData.CoverURL, settings.CheckArtworkDimensions)...)
+	}
+	return issues, nil
+}
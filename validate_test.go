@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// validFeed returns a feed that passes every validation rule.
+func validFeed() *RSSFeed {
+	return &RSSFeed{Channel: Channel{
+		Title:            "Show",
+		Link:             "https://example.com/show",
+		Description:      "About the show",
+		ITunesImage:      Image{Href: "https://example.com/cover.jpg"},
+		ITunesCategories: []Category{{Text: "News"}},
+		ITunesOwner:      &Owner{Name: "Publisher"},
+		Items: []Item{{
+			Title:     "Episode",
+			PubDate:   "Mon, 01 Jan 2024 10:00:00 +0000",
+			GUID:      GUID{Value: "ep-1"},
+			Enclosure: Enclosure{URL: "https://cdn.example.com/ep.mp3", Length: "1000", Type: "audio/mpeg"},
+		}},
+	}}
+}
+
+func TestValidateFeed(t *testing.T) {
+	if issues := validateFeed(validFeed()); len(issues) != 0 {
+		t.Fatalf("validateFeed() of a valid feed = %v, want no issues", issues)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*RSSFeed)
+		want   feedIssue
+	}{
+		{"title", func(f *RSSFeed) { f.Channel.Title = "" }, feedIssue{severityError, "channel title is missing"}},
+		{"description", func(f *RSSFeed) { f.Channel.Description = "" }, feedIssue{severityError, "channel description is missing"}},
+		{"image", func(f *RSSFeed) { f.Channel.ITunesImage.Href = "" }, feedIssue{severityError, "channel itunes:image is missing"}},
+		{"category", func(f *RSSFeed) { f.Channel.ITunesCategories = nil }, feedIssue{severityError, "channel itunes:category is missing"}},
+		{"owner", func(f *RSSFeed) { f.Channel.ITunesOwner = nil }, feedIssue{severityWarning, "channel itunes:owner is missing"}},
+		{"owner name", func(f *RSSFeed) { f.Channel.ITunesOwner.Name = "" }, feedIssue{severityWarning, "channel itunes:owner is missing"}},
+		{"link", func(f *RSSFeed) { f.Channel.Link = "" }, feedIssue{severityWarning, "channel link is missing"}},
+		{"no episodes", func(f *RSSFeed) { f.Channel.Items = nil }, feedIssue{severityWarning, "feed has no episodes"}},
+		{"episode title", func(f *RSSFeed) { f.Channel.Items[0].Title = "" }, feedIssue{severityError, "episode ep-1 has no title"}},
+		{"enclosure URL", func(f *RSSFeed) { f.Channel.Items[0].Enclosure.URL = "" }, feedIssue{severityError, `episode "Episode" has no enclosure URL`}},
+		{"enclosure length", func(f *RSSFeed) { f.Channel.Items[0].Enclosure.Length = "" }, feedIssue{severityWarning, `episode "Episode" has no enclosure length`}},
+		{"GUID", func(f *RSSFeed) { f.Channel.Items[0].GUID.Value = "" }, feedIssue{severityWarning, `episode "Episode" has no GUID`}},
+		{"pubDate", func(f *RSSFeed) { f.Channel.Items[0].PubDate = "" }, feedIssue{severityWarning, `episode "Episode" has no pubDate`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed := validFeed()
+			tt.mutate(feed)
+			issues := validateFeed(feed)
+			if len(issues) != 1 || issues[0] != tt.want {
+				t.Errorf("validateFeed() = %v, want [%v]", issues, tt.want)
+			}
+			if got, want := hasValidationErrors(issues), tt.want.Severity == severityError; got != want {
+				t.Errorf("hasValidationErrors() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateSeriesUsesUploadPipeline(t *testing.T) {
+	settings := apiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {"guid": "show", "title": "Show", "description": "About",
+			"link": "https://example.com", "cover_url": "https://example.com/cover.jpg",
+			"categories": ["News"], "author": "Publisher", "episodes": [
+			{"guid": "kept", "title": "Kept", "tags": ["news"], "publication_date": "2024-01-01T10:00:00Z",
+			 "audio_url": "https://cdn.example.com/kept.mp3", "audio_length": 1000},
+			{"guid": "filtered", "tags": ["sports"], "publication_date": "2024-01-02T10:00:00Z",
+			 "audio_url": "https://cdn.example.com/filtered.mp3", "audio_length": 1000}
+		]}}`))
+	})
+	settings.IncludeTags = []string{"news"}
+	series := Series{GUID: "show", S3Path: "s3://bucket/show.rss"}
+
+	issues, err := validateSeries(context.Background(), series, settings)
+	if err != nil {
+		t.Fatalf("validateSeries() error = %v", err)
+	}
+	if hasValidationErrors(issues) {
+		t.Errorf("validateSeries() = %v, want the untitled episode filtered out by tag", issues)
+	}
+
+	settings.MaxFeedBytes = 100
+	issues, err = validateSeries(context.Background(), series, settings)
+	if err != nil {
+		t.Fatalf("validateSeries() error = %v", err)
+	}
+	found := false
+	for _, issue := range issues {
+		if issue.Severity == severityError && strings.Contains(issue.Message, "max_feed_bytes") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("validateSeries() = %v, want an error for exceeding max_feed_bytes", issues)
+	}
+}
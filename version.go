@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// Set at build time, e.g.
+// go build -ldflags "-X main.version=v1.2.0 -X main.commit=abc123 -X main.date=2024-01-01"
+var (
+	version = "dev"
+	commit  = ""
+	date    = ""
+)
+
+// versionString describes the build. Without ldflags the commit and date
+// fall back to the VCS information Go embeds in module builds.
+func versionString() string {
+	revision, buildDate := commit, date
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if revision == "" {
+					revision = setting.Value
+				}
+			case "vcs.time":
+				if buildDate == "" {
+					buildDate = setting.Value
+				}
+			}
+		}
+	}
+	if revision == "" {
+		revision = "unknown"
+	}
+	if buildDate == "" {
+		buildDate = "unknown"
+	}
+	return fmt.Sprintf("sumppi %s (commit %s, built %s)", version, revision, buildDate)
+}